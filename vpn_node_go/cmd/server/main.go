@@ -5,12 +5,24 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"dvpn-node/internal/alerts"
 	"dvpn-node/internal/api"
+	"dvpn-node/internal/billing"
 	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/exit"
+	"dvpn-node/internal/firewall"
+	"dvpn-node/internal/heartbeat"
+	"dvpn-node/internal/ipam"
+	"dvpn-node/internal/session"
+	"dvpn-node/internal/shaping"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/tickets"
 	"dvpn-node/internal/types"
 	"dvpn-node/internal/wireguard"
 
@@ -35,26 +47,99 @@ func main() {
 
 	// Load configuration
 	config := &types.NodeConfig{
-		RPCURL:           getEnv("RPC_URL", "https://testnet-rpc.mawari.network"),
-		PrivateKey:       getEnv("PRIVATE_KEY", ""),
-		TokenAddress:     getEnv("TOKEN_ADDRESS", ""),
-		NodeRegistryAddr: getEnv("NODE_REGISTRY_ADDRESS", ""),
-		PaymentHubAddr:   getEnv("PAYMENT_HUB_ADDRESS", ""),
-		WGInterface:      getEnv("WG_INTERFACE", "wg0"),
-		WGPort:           getEnvAsInt("WG_PORT", 51820),
-		WGPrivateKey:     getEnv("WG_PRIVATE_KEY", ""),
-		WGPublicKey:      getEnv("WG_PUBLIC_KEY", ""),
-		WGSubnet:         getEnv("WG_SUBNET", "10.0.0.1/24"),
-		APIPort:          getEnvAsInt("API_PORT", 3000),
-		EnableWebSocket:  getEnvAsBool("ENABLE_WEBSOCKET", true),
-		NodeLocation:     getEnv("NODE_LOCATION", "Toronto, Canada"),
-		NodeBandwidth:    getEnvAsInt64("NODE_BANDWIDTH", 1000000000),
-		MinStake:         getEnv("MIN_STAKE", "1000000000000000000000"),
+		RPCURL:                getEnv("RPC_URL", "https://testnet-rpc.mawari.network"),
+		RPCURLs:               getEnvAsSlice("RPC_URLS", nil),
+		RPCTimeout:            getEnvAsDuration("RPC_TIMEOUT", 10*time.Second),
+		RPCHealthInterval:     getEnvAsDuration("RPC_HEALTH_INTERVAL", 15*time.Second),
+		RPCMaxBlockLag:        getEnvAsUint64("RPC_MAX_BLOCK_LAG", 5),
+		PrivateKey:            getEnv("PRIVATE_KEY", ""),
+		TokenAddress:          getEnv("TOKEN_ADDRESS", ""),
+		NodeRegistryAddr:      getEnv("NODE_REGISTRY_ADDRESS", ""),
+		PaymentHubAddr:        getEnv("PAYMENT_HUB_ADDRESS", ""),
+		KeystorePath:          getEnv("KEYSTORE_PATH", ""),
+		KeystorePasswordFile:  getEnv("KEYSTORE_PASSWORD_FILE", ""),
+		RemoteSignerURL:       getEnv("REMOTE_SIGNER_URL", ""),
+		RemoteSignerAddress:   getEnv("REMOTE_SIGNER_ADDRESS", ""),
+		RemoteSignerMethod:    getEnv("REMOTE_SIGNER_METHOD", "eth_signTransaction"),
+		TxConfirmations:       getEnvAsUint64("TX_CONFIRMATIONS", 1),
+		TxStuckTimeout:        getEnvAsDuration("TX_STUCK_TIMEOUT", 2*time.Minute),
+		TxFeeBumpPercent:      getEnvAsInt("TX_FEE_BUMP_PERCENT", 15),
+		TxMaxFeeCap:           getEnv("TX_MAX_FEE_CAP", ""),
+		WithdrawEnabled:       getEnvAsBool("WITHDRAW_ENABLED", true),
+		WithdrawInterval:      getEnvAsDuration("WITHDRAW_INTERVAL", 5*time.Minute),
+		WithdrawThreshold:     getEnv("WITHDRAW_THRESHOLD", "10000000000000000000"),
+		WithdrawEndWindow:     getEnvAsDuration("WITHDRAW_END_WINDOW", time.Hour),
+		WithdrawMaxGasShare:   getEnvAsFloat("WITHDRAW_MAX_GAS_SHARE", 0.05),
+		WithdrawGasTokenRate:  getEnv("WITHDRAW_GAS_TOKEN_RATE", "1"),
+		WithdrawStartBlock:    getEnvAsUint64("WITHDRAW_START_BLOCK", 0),
+		WithdrawConfirmations: getEnvAsUint64("WITHDRAW_CONFIRMATIONS", 12),
+		IndexerEnabled:        getEnvAsBool("INDEXER_ENABLED", true),
+		IndexerStartBlock:     getEnvAsUint64("INDEXER_START_BLOCK", 0),
+		IndexerConfirmations:  getEnvAsUint64("INDEXER_CONFIRMATIONS", 12),
+		IndexerPollInterval:   getEnvAsDuration("INDEXER_POLL_INTERVAL", 15*time.Second),
+		WebhookURLs:           getEnvAsSlice("WEBHOOK_URLS", nil),
+		WebhookSecret:         getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxRetries:     getEnvAsInt("WEBHOOK_MAX_RETRIES", 5),
+		WebhookTimeout:        getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WGInterface:           getEnv("WG_INTERFACE", "wg0"),
+		WGPort:                getEnvAsInt("WG_PORT", 51820),
+		WGPrivateKey:          getEnv("WG_PRIVATE_KEY", ""),
+		WGPublicKey:           getEnv("WG_PUBLIC_KEY", ""),
+		WGSubnet:              getEnv("WG_SUBNET", "10.0.0.1/24"),
+		WGAdoptOrphans:        getEnvAsBool("WG_ADOPT_ORPHANS", false),
+		WGBackend:             getEnv("WG_BACKEND", "auto"),
+		WGMTU:                 getEnvAsInt("WG_MTU", 1420),
+		WGRoutes:              getEnvAsSlice("WG_ROUTES", nil),
+		WGHandshakeTimeout:    getEnvAsDuration("WG_HANDSHAKE_TIMEOUT", 3*time.Minute),
+		WGIdleTimeout:         getEnvAsDuration("WG_IDLE_TIMEOUT", 5*time.Minute),
+		WGStaleTimeout:        getEnvAsDuration("WG_STALE_TIMEOUT", 30*time.Minute),
+		WGReapInterval:        getEnvAsDuration("WG_REAP_INTERVAL", time.Minute),
+		WGReapIdleTimeout:     getEnvAsDuration("WG_REAP_IDLE_TIMEOUT", time.Hour),
+		WGMaxPeerLifetime:     getEnvAsDuration("WG_MAX_PEER_LIFETIME", 0),
+		QuotaEnabled:          getEnvAsBool("QUOTA_ENABLED", false),
+		QuotaSessionBytes:     getEnvAsInt64("QUOTA_SESSION_BYTES", 0),
+		QuotaDailyBytes:       getEnvAsInt64("QUOTA_DAILY_BYTES", 0),
+		QuotaAction:           getEnv("QUOTA_ACTION", "disconnect"),
+		QuotaThrottleRate:     getEnvAsUint64("QUOTA_THROTTLE_RATE", 131072),
+		ShapingEnabled:        getEnvAsBool("SHAPING_ENABLED", false),
+		ShapingBackend:        getEnv("SHAPING_BACKEND", "netlink"),
+		ShapingDefaultUp:      getEnvAsUint64("SHAPING_DEFAULT_UP", 0),
+		ShapingDefaultDown:    getEnvAsUint64("SHAPING_DEFAULT_DOWN", 0),
+		ShapingTiers:          getEnvAsSlice("SHAPING_TIERS", nil),
+		ShapingDefaultTier:    getEnv("SHAPING_DEFAULT_TIER", ""),
+		FirewallEnabled:       getEnvAsBool("FIREWALL_ENABLED", false),
+		FirewallEgress:        getEnv("FIREWALL_EGRESS_INTERFACE", ""),
+		FirewallIsolatePeers:  getEnvAsBool("FIREWALL_ISOLATE_PEERS", true),
+		FirewallBlockedPorts:  getEnvAsSlice("FIREWALL_BLOCKED_PORTS", nil),
+		NodePublicEndpoint:    getEnv("NODE_PUBLIC_ENDPOINT", ""),
+		ClientDNS:             getEnvAsSlice("CLIENT_DNS", []string{"1.1.1.1"}),
+		ClientKeepalive:       getEnvAsInt("CLIENT_KEEPALIVE", 25),
+		ClientSplitAllowedIPs: getEnvAsSlice("CLIENT_SPLIT_ALLOWED_IPS", nil),
+		SessionMinAmount:      getEnv("SESSION_MIN_AMOUNT", "0"),
+		SessionCheckInterval:  getEnvAsDuration("SESSION_CHECK_INTERVAL", 30*time.Second),
+		DataDir:               getEnv("DATA_DIR", "./data"),
+		APIPort:               getEnvAsInt("API_PORT", 3000),
+		EnableWebSocket:       getEnvAsBool("ENABLE_WEBSOCKET", true),
+		AdminToken:            getEnv("ADMIN_TOKEN", ""),
+		NodeLocation:          getEnv("NODE_LOCATION", "Toronto, Canada"),
+		NodeBandwidth:         getEnvAsInt64("NODE_BANDWIDTH", 1000000000),
+		MinStake:              getEnv("MIN_STAKE", "1000000000000000000000"),
+		HeartbeatEnabled:      getEnvAsBool("HEARTBEAT_ENABLED", true),
+		HeartbeatInterval:     getEnvAsDuration("HEARTBEAT_INTERVAL", time.Hour),
+		HeartbeatBatchSize:    getEnvAsInt("HEARTBEAT_BATCH_SIZE", 6),
+		HeartbeatURLs:         getEnvAsSlice("HEARTBEAT_URLS", nil),
+		BillingEnabled:        getEnvAsBool("BILLING_ENABLED", true),
+		BillingInterval:       getEnvAsDuration("BILLING_INTERVAL", time.Minute),
+		BillingPricePerGB:     getEnv("BILLING_PRICE_PER_GB", "1000000000000000000"),
+		BillingGracePeriod:    getEnvAsDuration("BILLING_GRACE_PERIOD", 5*time.Minute),
+		BillingUnderpayAction: getEnv("BILLING_UNDERPAY_ACTION", "remove"),
+		BillingThrottleRate:   getEnvAsUint64("BILLING_THROTTLE_RATE", 131072),
+		ExitGracePeriod:       getEnvAsDuration("EXIT_GRACE_PERIOD", 10*time.Minute),
 	}
 
 	// Validate required configuration
-	if config.PrivateKey == "" {
-		logger.Fatal("PRIVATE_KEY environment variable is required")
+	if config.PrivateKey == "" && config.KeystorePath == "" && config.RemoteSignerURL == "" {
+		logger.Fatal("One of REMOTE_SIGNER_URL, KEYSTORE_PATH or PRIVATE_KEY environment variables is required")
 	}
 	if config.TokenAddress == "" {
 		logger.Fatal("TOKEN_ADDRESS environment variable is required")
@@ -72,10 +157,35 @@ func main() {
 		logger.Fatal("WG_PUBLIC_KEY environment variable is required")
 	}
 
+	// Intervals drive tickers, which panic unless they are positive
+	for _, interval := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"RPC_HEALTH_INTERVAL", config.RPCHealthInterval},
+		{"WITHDRAW_INTERVAL", config.WithdrawInterval},
+		{"INDEXER_POLL_INTERVAL", config.IndexerPollInterval},
+		{"WG_REAP_INTERVAL", config.WGReapInterval},
+		{"SESSION_CHECK_INTERVAL", config.SessionCheckInterval},
+		{"HEARTBEAT_INTERVAL", config.HeartbeatInterval},
+		{"BILLING_INTERVAL", config.BillingInterval},
+	} {
+		if interval.value <= 0 {
+			logger.Fatalf("%s must be a positive duration, got %s", interval.name, interval.value)
+		}
+	}
+
 	logger.Info("Configuration loaded successfully")
 
+	// Open local database
+	db, err := store.Open(filepath.Join(config.DataDir, "node.db"))
+	if err != nil {
+		logger.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
 	// Initialize blockchain service
-	blockchainService, err := blockchain.NewBlockchainService(config, logger)
+	blockchainService, err := blockchain.NewBlockchainService(config, db, logger)
 	if err != nil {
 		logger.Fatalf("Failed to initialize blockchain service: %v", err)
 	}
@@ -83,8 +193,14 @@ func main() {
 
 	logger.Info("Blockchain service initialized")
 
+	// Initialize tunnel address pool
+	addressPool, err := ipam.NewAllocator(config, db, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize address pool: %v", err)
+	}
+
 	// Initialize WireGuard service
-	wireguardService, err := wireguard.NewWireGuardService(config, logger)
+	wireguardService, err := wireguard.NewWireGuardService(config, addressPool, db, logger)
 	if err != nil {
 		logger.Fatalf("Failed to initialize WireGuard service: %v", err)
 	}
@@ -92,16 +208,145 @@ func main() {
 
 	logger.Info("WireGuard service initialized")
 
+	// Initialize exit NAT, forwarding and peer isolation
+	var firewallManager *firewall.Manager
+	if config.FirewallEnabled {
+		firewallManager, err = firewall.NewManager(config, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize firewall: %v", err)
+		}
+		if err := firewallManager.Apply(); err != nil {
+			logger.Fatalf("Failed to apply firewall rules: %v", err)
+		}
+		defer func() {
+			if err := firewallManager.Cleanup(); err != nil {
+				logger.Errorf("Failed to clean up firewall rules: %v", err)
+			}
+		}()
+	} else {
+		logger.Warn("Firewall management disabled, forwarding and NAT for peers must be set up on the host")
+	}
+
+	// Initialize withdrawal scheduler, also used to withdraw everything on exit
+	withdrawalScheduler, err := blockchain.NewWithdrawalScheduler(config, blockchainService, db, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize withdrawal scheduler: %v", err)
+	}
+
+	// Initialize exit manager
+	exitManager, err := exit.NewManager(blockchainService, wireguardService, withdrawalScheduler, db, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize exit manager: %v", err)
+	}
+
+	// Initialize heartbeat service
+	heartbeatService := heartbeat.NewService(config, blockchainService, wireguardService, db, logger)
+
+	// Initialize metered billing
+	billingEngine, err := billing.NewEngine(config, blockchainService, wireguardService, db, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize billing engine: %v", err)
+	}
+
+	// Initialize payment ticket verification
+	ticketService := tickets.NewService(blockchainService, wireguardService, billingEngine, db, logger)
+
+	// Initialize pay-to-connect sessions
+	sessionManager, err := session.NewManager(config, blockchainService, wireguardService, billingEngine, db, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize session manager: %v", err)
+	}
+
+	// Initialize per-peer rate limiting, before peers are reconciled so they are shaped
+	var shaper *shaping.Shaper
+	if config.ShapingEnabled {
+		backend, err := shaping.NewBackend(config)
+		if err != nil {
+			logger.Fatalf("Failed to initialize traffic shaping backend: %v", err)
+		}
+		shaper, err = shaping.NewShaper(config, backend, db, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize traffic shaping: %v", err)
+		}
+
+		wireguardService.SetShaper(shaper)
+		wireguardService.SetThrottler(shaper)
+		billingEngine.SetThrottler(shaper)
+	}
+
+	// Reconcile the device with stored peers and open sessions
+	sessionPeers, err := sessionManager.Peers()
+	if err != nil {
+		logger.Fatalf("Failed to load sessions: %v", err)
+	}
+	if _, err := wireguardService.Reconcile(sessionPeers); err != nil {
+		logger.Errorf("Failed to reconcile WireGuard peers: %v", err)
+	}
+
 	// Initialize API server
-	apiServer := api.NewServer(config, logger, blockchainService, wireguardService)
+	apiServer := api.NewServer(config, logger, blockchainService, wireguardService, exitManager, heartbeatService, ticketService, billingEngine, sessionManager, shaper, firewallManager)
+	billingEngine.SetNotifier(apiServer.Broadcast)
+	sessionManager.SetNotifier(apiServer.Broadcast)
+	wireguardService.SetNotifier(apiServer.Broadcast)
+	wireguardService.SetExpiryChecker(sessionManager)
+	wireguardService.SetPrepaidQuota(billingEngine)
+	withdrawalScheduler.SetNotifier(apiServer.Broadcast)
+	exitManager.SetNotifier(apiServer.Broadcast)
 
 	// Start background tasks
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Start RPC health checks and transaction tracking
+	go blockchainService.RPCPool().Run(ctx)
+	go blockchainService.TxManager().Run(ctx)
+
 	// Start stats monitoring
 	go monitorStats(ctx, logger, wireguardService, blockchainService)
 
+	// Start contract event indexer and the alerts built on it
+	if config.IndexerEnabled {
+		indexer, err := blockchain.NewIndexer(config, blockchainService, db, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize event indexer: %v", err)
+		}
+
+		alertService, err := alerts.NewService(config, indexer, blockchainService, db, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize alerts: %v", err)
+		}
+		alertService.SetNotifier(apiServer.Broadcast)
+		if err := alertService.Start(ctx); err != nil {
+			logger.Fatalf("Failed to start alerts: %v", err)
+		}
+
+		go indexer.Run(ctx)
+	} else {
+		logger.Warn("Event indexer disabled, slashing and reputation alerts are unavailable")
+	}
+
+	// Start automatic stream withdrawals
+	if config.WithdrawEnabled {
+		go withdrawalScheduler.Run(ctx)
+	}
+
+	// Start liveness heartbeats
+	if config.HeartbeatEnabled {
+		go heartbeatService.Run(ctx)
+	}
+
+	// Start metered billing
+	if config.BillingEnabled {
+		go billingEngine.Run(ctx)
+	}
+
+	// Start session teardown and the dead peer reaper
+	go sessionManager.Run(ctx)
+	go wireguardService.RunReaper(ctx)
+
+	// Start exit processing
+	go exitManager.Run(ctx)
+
 	// Start API server
 	go func() {
 		if err := apiServer.Start(); err != nil {
@@ -186,6 +431,46 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvAsUint64(key string, defaultValue uint64) uint64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -194,4 +479,3 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
- 
\ No newline at end of file
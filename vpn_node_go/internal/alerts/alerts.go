@@ -0,0 +1,155 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	alertsBucket = "alerts"
+
+	// alertsFromKey stores the block after which indexed events are alerted
+	alertsFromKey = "from"
+)
+
+// alertTypes maps NodeRegistry events to the message types sent to clients
+var alertTypes = map[string]string{
+	"NodeSlashed":       "node_slashed",
+	"ReputationUpdated": "reputation_updated",
+	"NodeUnregistered":  "node_unregistered",
+}
+
+// Service turns NodeRegistry events about this node into operator alerts.
+// Only events after the chain head at the first start are alerted, so the
+// initial backfill does not replay the node's history as live alerts.
+type Service struct {
+	indexer    *blockchain.Indexer
+	blockchain *blockchain.BlockchainService
+	store      *store.Store
+	node       string
+	webhooks   *WebhookDispatcher
+	notify     func(types.WebSocketMessage)
+	logger     *logrus.Logger
+}
+
+// NewService creates an alert service for the node wallet
+func NewService(config *types.NodeConfig, indexer *blockchain.Indexer, blockchain *blockchain.BlockchainService, db *store.Store, logger *logrus.Logger) (*Service, error) {
+	webhooks, err := NewWebhookDispatcher(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		indexer:    indexer,
+		blockchain: blockchain,
+		store:      db,
+		node:       blockchain.GetWalletAddress(),
+		webhooks:   webhooks,
+		notify:     func(types.WebSocketMessage) {},
+		logger:     logger,
+	}, nil
+}
+
+// SetNotifier sets the function used to push alerts to WebSocket clients
+func (s *Service) SetNotifier(notify func(types.WebSocketMessage)) {
+	s.notify = notify
+}
+
+// Start subscribes to the indexer and forwards alerts until ctx is cancelled.
+// The subscription is registered before Start returns, so no events are
+// missed if the indexer is started afterwards.
+func (s *Service) Start(ctx context.Context) error {
+	from, err := s.alertsFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(alertTypes))
+	for name := range alertTypes {
+		names = append(names, name)
+	}
+
+	events, cancel := s.indexer.Subscribe(names...)
+	s.webhooks.Start(ctx)
+
+	go func() {
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				node, _ := event.Data["node"].(string)
+				if !strings.EqualFold(node, s.node) {
+					continue
+				}
+				if event.BlockNumber <= from {
+					s.logger.Debugf("Skipping historical %s event at block %d", event.Name, event.BlockNumber)
+					continue
+				}
+				s.dispatch(event)
+			}
+		}
+	}()
+	return nil
+}
+
+// alertsFrom returns the block after which events are alerted, recording the
+// current chain head on the first start
+func (s *Service) alertsFrom(ctx context.Context) (uint64, error) {
+	var from uint64
+	found, err := s.store.Get(alertsBucket, alertsFromKey, &from)
+	if err != nil || found {
+		return from, err
+	}
+
+	from, err = s.blockchain.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+	if err := s.store.Put(alertsBucket, alertsFromKey, from); err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Alerting on node events after block %d", from)
+	return from, nil
+}
+
+// dispatch sends a typed alert for event to WebSocket clients and webhooks
+func (s *Service) dispatch(event types.ChainEvent) {
+	alert := types.NodeAlert{
+		Event:       event.Name,
+		Node:        s.node,
+		BlockNumber: event.BlockNumber,
+		TxHash:      event.TxHash,
+		Removed:     event.Removed,
+	}
+	if amount, ok := event.Data["amount"].(string); ok {
+		alert.SlashedAmount = amount
+	}
+	if reputation, ok := event.Data["newReputation"].(string); ok {
+		alert.NewReputation = reputation
+	}
+
+	if event.Removed {
+		s.logger.Warnf("%s alert in tx %s was rolled back by a chain reorg", event.Name, event.TxHash)
+	} else {
+		s.logger.Warnf("Node alert: %s in tx %s", event.Name, event.TxHash)
+	}
+
+	message := types.WebSocketMessage{
+		Type:    alertTypes[event.Name],
+		Payload: alert,
+	}
+
+	s.notify(message)
+	s.webhooks.Send(message)
+}
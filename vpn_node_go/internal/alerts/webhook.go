@@ -0,0 +1,173 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// initialRetryDelay is the delay before the first webhook retry; it doubles on each attempt
+	initialRetryDelay = time.Second
+
+	// webhookQueueSize is how many alerts may wait for delivery to one endpoint
+	webhookQueueSize = 256
+)
+
+// delivery is an alert waiting to be posted to an endpoint
+type delivery struct {
+	event string
+	body  []byte
+}
+
+// WebhookDispatcher delivers alerts to HTTP endpoints. Each request carries
+// an X-DVPN-Signature header with the hex HMAC-SHA256 of
+// "<X-DVPN-Timestamp>.<body>" keyed with the shared secret. Every endpoint
+// has its own queue and worker, so a slow endpoint delays neither the others
+// nor the caller, and alerts reach each endpoint in order.
+type WebhookDispatcher struct {
+	urls       []string
+	queues     []chan delivery
+	secret     []byte
+	maxRetries int
+	retryDelay time.Duration
+	client     *http.Client
+	logger     *logrus.Logger
+}
+
+// NewWebhookDispatcher creates a dispatcher for the configured webhook
+// endpoints. Payloads are always signed, so endpoints require a secret.
+func NewWebhookDispatcher(config *types.NodeConfig, logger *logrus.Logger) (*WebhookDispatcher, error) {
+	if len(config.WebhookURLs) > 0 && config.WebhookSecret == "" {
+		return nil, fmt.Errorf("WEBHOOK_SECRET is required when WEBHOOK_URLS is set")
+	}
+
+	queues := make([]chan delivery, len(config.WebhookURLs))
+	for i := range queues {
+		queues[i] = make(chan delivery, webhookQueueSize)
+	}
+
+	return &WebhookDispatcher{
+		urls:       config.WebhookURLs,
+		queues:     queues,
+		secret:     []byte(config.WebhookSecret),
+		maxRetries: config.WebhookMaxRetries,
+		retryDelay: initialRetryDelay,
+		client:     &http.Client{Timeout: config.WebhookTimeout},
+		logger:     logger,
+	}, nil
+}
+
+// Start runs a delivery worker for every endpoint until ctx is cancelled
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	for i, url := range d.urls {
+		go d.worker(ctx, url, d.queues[i])
+	}
+}
+
+// Send queues message for delivery to every endpoint without blocking. An
+// alert is dropped, with an error logged, when an endpoint's queue is full.
+func (d *WebhookDispatcher) Send(message types.WebSocketMessage) {
+	if len(d.urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		d.logger.Errorf("Failed to encode webhook payload: %v", err)
+		return
+	}
+
+	for i, url := range d.urls {
+		select {
+		case d.queues[i] <- delivery{event: message.Type, body: body}:
+		default:
+			d.logger.Errorf("Webhook queue for %s is full, dropping %s alert", url, message.Type)
+		}
+	}
+}
+
+// worker delivers the alerts queued for url one at a time
+func (d *WebhookDispatcher) worker(ctx context.Context, url string, queue <-chan delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case next := <-queue:
+			d.deliver(ctx, url, next.event, next.body)
+		}
+	}
+}
+
+// deliver posts body to url with exponential backoff until it succeeds or retries run out
+func (d *WebhookDispatcher) deliver(ctx context.Context, url, event string, body []byte) {
+	delay := d.retryDelay
+
+	for attempt := 0; ; attempt++ {
+		retry, err := d.post(ctx, url, event, body)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt >= d.maxRetries {
+			d.logger.Errorf("Failed to deliver %s webhook to %s: %v", event, url, err)
+			return
+		}
+
+		d.logger.Warnf("Webhook %s to %s failed (attempt %d): %v", event, url, attempt+1, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends a single signed request and reports whether a failure is worth retrying
+func (d *WebhookDispatcher) post(ctx context.Context, url, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-DVPN-Event", event)
+	req.Header.Set("X-DVPN-Timestamp", timestamp)
+	req.Header.Set("X-DVPN-Signature", "sha256="+d.sign(timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// sign computes the payload signature
+func (d *WebhookDispatcher) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
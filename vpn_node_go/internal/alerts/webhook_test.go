@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const testSecret = "webhook-secret"
+
+func newTestDispatcher(t *testing.T, url string, maxRetries int) *WebhookDispatcher {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	d, err := NewWebhookDispatcher(&types.NodeConfig{
+		WebhookURLs:       []string{url},
+		WebhookSecret:     testSecret,
+		WebhookMaxRetries: maxRetries,
+		WebhookTimeout:    time.Second,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+	d.retryDelay = time.Millisecond
+	return d
+}
+
+func TestNewWebhookDispatcherRequiresSecret(t *testing.T) {
+	_, err := NewWebhookDispatcher(&types.NodeConfig{WebhookURLs: []string{"http://localhost"}}, logrus.New())
+	if err == nil {
+		t.Fatal("NewWebhookDispatcher accepted webhook URLs without a secret")
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	type request struct {
+		event, timestamp, signature string
+		body                        []byte
+	}
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{
+			event:     r.Header.Get("X-DVPN-Event"),
+			timestamp: r.Header.Get("X-DVPN-Timestamp"),
+			signature: r.Header.Get("X-DVPN-Signature"),
+			body:      body,
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newTestDispatcher(t, server.URL, 0)
+	d.Start(ctx)
+	d.Send(types.WebSocketMessage{Type: "node_slashed", Payload: types.NodeAlert{Event: "NodeSlashed"}})
+
+	var got request
+	select {
+	case got = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(got.timestamp + "." + string(got.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got.event != "node_slashed" {
+		t.Fatalf("X-DVPN-Event = %q, want node_slashed", got.event)
+	}
+	if got.timestamp == "" || got.signature != want {
+		t.Fatalf("X-DVPN-Signature = %q, want %q", got.signature, want)
+	}
+}
+
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, server.URL, 5)
+	d.deliver(context.Background(), server.URL, "node_slashed", []byte("{}"))
+
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("got %d attempts, want 3", got)
+	}
+}
+
+func TestWebhookGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, server.URL, 2)
+	d.deliver(context.Background(), server.URL, "node_slashed", []byte("{}"))
+
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("got %d attempts, want the first and 2 retries", got)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, server.URL, 5)
+	d.deliver(context.Background(), server.URL, "node_slashed", []byte("{}"))
+
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("got %d attempts, want 1", got)
+	}
+}
+
+func TestWebhookSendDoesNotBlock(t *testing.T) {
+	d := newTestDispatcher(t, "http://127.0.0.1:1", 0)
+
+	// No worker drains the queue, so sends past its size are dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < webhookQueueSize+10; i++ {
+			d.Send(types.WebSocketMessage{Type: "reputation_updated"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send blocked on a full queue")
+	}
+}
@@ -1,19 +1,32 @@
 package api
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"dvpn-node/internal/billing"
 	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/clientconfig"
+	"dvpn-node/internal/exit"
+	"dvpn-node/internal/firewall"
+	"dvpn-node/internal/heartbeat"
+	"dvpn-node/internal/ipam"
+	"dvpn-node/internal/session"
+	"dvpn-node/internal/shaping"
+	"dvpn-node/internal/tickets"
 	"dvpn-node/internal/types"
 	"dvpn-node/internal/wireguard"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,19 +36,48 @@ type Server struct {
 	logger           *logrus.Logger
 	blockchain       *blockchain.BlockchainService
 	wireguard        *wireguard.WireGuardService
+	exit             *exit.Manager
+	heartbeat        *heartbeat.Service
+	tickets          *tickets.Service
+	billing          *billing.Engine
+	sessions         *session.Manager
+	shaper           *shaping.Shaper
+	firewall         *firewall.Manager
 	upgrader         websocket.Upgrader
-	wsConnections    map[*websocket.Conn]bool
+	wsConnections    map[*websocket.Conn]*wsConnection
 	wsConnectionsMux sync.RWMutex
 }
 
+// wsConnection is a WebSocket client. A connection supports only one
+// concurrent writer, so every write goes through writeJSON.
+type wsConnection struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// writeJSON sends v to the client
+func (c *wsConnection) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteJSON(v)
+}
+
 // NewServer creates a new API server
-func NewServer(config *types.NodeConfig, logger *logrus.Logger, blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService) *Server {
+func NewServer(config *types.NodeConfig, logger *logrus.Logger, blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService, exit *exit.Manager, heartbeat *heartbeat.Service, tickets *tickets.Service, billing *billing.Engine, sessions *session.Manager, shaper *shaping.Shaper, firewall *firewall.Manager) *Server {
 	return &Server{
 		config:        config,
 		logger:        logger,
 		blockchain:    blockchain,
 		wireguard:     wireguard,
-		wsConnections: make(map[*websocket.Conn]bool),
+		exit:          exit,
+		heartbeat:     heartbeat,
+		tickets:       tickets,
+		billing:       billing,
+		sessions:      sessions,
+		shaper:        shaper,
+		firewall:      firewall,
+		wsConnections: make(map[*websocket.Conn]*wsConnection),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for development
@@ -69,10 +111,18 @@ func (s *Server) Start() error {
 		api.GET("/node/status", s.getNodeStatus)
 		api.GET("/node/info", s.getNodeInfo)
 		api.POST("/node/register", s.registerNode)
+		api.GET("/node/stake", s.getStake)
+		api.POST("/node/stake", s.topUpStake)
+		api.GET("/node/exit", s.getExitStatus)
+		api.POST("/node/exit", s.requireAdmin, s.startExit)
+		api.GET("/node/heartbeat", s.getHeartbeatStatus)
+		api.GET("/node/heartbeats", s.getPendingHeartbeats)
 
 		// Peer management
 		api.GET("/peers", s.getPeers)
 		api.POST("/peers", s.addPeer)
+		api.POST("/peers/config", s.getClientConfig)
+		api.GET("/peers/reconcile", s.getReconcileReport)
 		api.DELETE("/peers/:publicKey", s.removePeer)
 		api.GET("/peers/:publicKey", s.getPeer)
 
@@ -82,9 +132,35 @@ func (s *Server) Start() error {
 		api.GET("/blockchain/stream/:streamId", s.getStream)
 		api.POST("/blockchain/withdraw", s.withdrawFromStream)
 
+		// Pay-to-connect sessions
+		api.POST("/sessions", s.createSession)
+		api.GET("/sessions", s.getSessions)
+		api.DELETE("/sessions", s.closeSession)
+
+		// Payment tickets
+		api.GET("/tickets/domain", s.getTicketDomain)
+		api.POST("/tickets", s.submitTicket)
+		api.GET("/tickets", s.getTickets)
+		api.GET("/tickets/settlements", s.getTicketSettlements)
+		api.POST("/tickets/settled", s.markTicketSettled)
+
+		// Metered billing
+		api.GET("/billing/accounts", s.getBillingAccounts)
+		api.POST("/billing/accounts", s.attachBillingAccount)
+		api.DELETE("/billing/accounts", s.detachBillingAccount)
+		api.GET("/billing/usage", s.getBillingUsage)
+
+		// Traffic shaping
+		api.GET("/shaping", s.getShaping)
+		api.PUT("/shaping", s.setShaping)
+
+		// Exit firewall
+		api.GET("/firewall", s.getFirewallStatus)
+
 		// Statistics
 		api.GET("/stats/bandwidth", s.getBandwidthStats)
 		api.GET("/stats/peers", s.getPeerStats)
+		api.GET("/stats/addresses", s.getAddressStats)
 	}
 
 	// WebSocket endpoint
@@ -126,6 +202,13 @@ func (s *Server) getNodeStatus(c *gin.Context) {
 func (s *Server) getNodeInfo(c *gin.Context) {
 	walletAddress := s.blockchain.GetWalletAddress()
 	nodeInfo, err := s.blockchain.GetNodeInfo(walletAddress)
+	if errors.Is(err, blockchain.ErrNodeNotRegistered) {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.APIResponse{
 			Success: false,
@@ -164,9 +247,11 @@ func (s *Server) registerNode(c *gin.Context) {
 		return
 	}
 
-	if err := s.blockchain.RegisterNode(request.Metadata, stake); err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+	result, err := s.blockchain.RegisterNode(request.Metadata, stake)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), types.APIResponse{
 			Success: false,
+			Data:    result,
 			Error:   err.Error(),
 		})
 		return
@@ -175,6 +260,200 @@ func (s *Server) registerNode(c *gin.Context) {
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
 		Message: "Node registered successfully",
+		Data:    result,
+	})
+}
+
+// getStake returns the stake held by the node in the registry
+func (s *Server) getStake(c *gin.Context) {
+	stake, err := s.blockchain.GetStake()
+	if errors.Is(err, blockchain.ErrNodeNotRegistered) {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    stake,
+	})
+}
+
+// topUpStake adds to the node stake
+func (s *Server) topUpStake(c *gin.Context) {
+	var request struct {
+		Amount string `json:"amount"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	amount, ok := new(big.Int).SetString(request.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid amount",
+		})
+		return
+	}
+
+	txHash, err := s.blockchain.TopUpStake(amount)
+	if errors.Is(err, blockchain.ErrStakeTopUpUnsupported) {
+		c.JSON(http.StatusNotImplemented, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Stake increased successfully",
+		Data: map[string]interface{}{
+			"txHash": txHash,
+		},
+	})
+}
+
+// requireAdmin rejects requests without the operator's ADMIN_TOKEN as a
+// bearer token. Operator-only routes are disabled when no token is set.
+func (s *Server) requireAdmin(c *gin.Context) {
+	if s.config.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, types.APIResponse{
+			Success: false,
+			Error:   "Operator routes are disabled, set ADMIN_TOKEN to enable them",
+		})
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, types.APIResponse{
+			Success: false,
+			Error:   "Invalid or missing operator token",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// getExitStatus returns the progress of the node exit
+func (s *Server) getExitStatus(c *gin.Context) {
+	status := s.exit.Status()
+	if status == nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "No exit in progress",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// startExit starts retiring the node: peers are drained after the grace
+// period, open streams are withdrawn and the node is unregistered
+func (s *Server) startExit(c *gin.Context) {
+	var request struct {
+		GracePeriod string `json:"gracePeriod"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	gracePeriod := s.config.ExitGracePeriod
+	if request.GracePeriod != "" {
+		parsed, err := time.ParseDuration(request.GracePeriod)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, types.APIResponse{
+				Success: false,
+				Error:   "Invalid grace period",
+			})
+			return
+		}
+		gracePeriod = parsed
+	}
+
+	status, err := s.exit.Start(gracePeriod)
+	if errors.Is(err, exit.ErrExitInProgress) || errors.Is(err, exit.ErrAlreadyExited) {
+		c.JSON(http.StatusConflict, types.APIResponse{
+			Success: false,
+			Data:    s.exit.Status(),
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, types.APIResponse{
+		Success: true,
+		Message: "Node exit started",
+		Data:    status,
+	})
+}
+
+// getHeartbeatStatus returns the state of the heartbeat service
+func (s *Server) getHeartbeatStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    s.heartbeat.Status(),
+	})
+}
+
+// getPendingHeartbeats returns signed heartbeats not yet published, for
+// collectors that pull instead of receiving batches
+func (s *Server) getPendingHeartbeats(c *gin.Context) {
+	heartbeats, err := s.heartbeat.Pending()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data: types.HeartbeatBatch{
+			Node:       s.blockchain.GetWalletAddress(),
+			Heartbeats: heartbeats,
+		},
 	})
 }
 
@@ -203,8 +482,9 @@ func (s *Server) addPeer(c *gin.Context) {
 		return
 	}
 
-	if err := s.wireguard.AddPeer(request.PublicKey, request.AllowedIPs); err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+	allowedIPs, err := s.wireguard.AddPeer(request.PublicKey, request.AllowedIPs)
+	if err != nil {
+		c.JSON(peerErrorStatus(err), types.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -212,17 +492,118 @@ func (s *Server) addPeer(c *gin.Context) {
 	}
 
 	// Broadcast to WebSocket clients
-	s.broadcastWebSocket(types.WebSocketMessage{
+	s.Broadcast(types.WebSocketMessage{
 		Type: "peer_added",
 		Payload: map[string]interface{}{
 			"publicKey":  request.PublicKey,
-			"allowedIPs": request.AllowedIPs,
+			"allowedIPs": allowedIPs,
 		},
 	})
 
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
 		Message: "Peer added successfully",
+		Data: map[string]interface{}{
+			"publicKey":  request.PublicKey,
+			"allowedIPs": allowedIPs,
+		},
+	})
+}
+
+// getClientConfig renders the client configuration of a peer as JSON, a
+// wg-quick file or a QR code
+func (s *Server) getClientConfig(c *gin.Context) {
+	var request struct {
+		PublicKey  string `json:"publicKey"`
+		PrivateKey string `json:"privateKey"` // optional, only used for rendering
+		Tunnel     string `json:"tunnel"`
+		Format     string `json:"format"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	peer, exists := s.wireguard.GetPeer(request.PublicKey)
+	if !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "Peer not found",
+		})
+		return
+	}
+
+	config, err := clientconfig.Build(s.config, s.wireguard.GetPublicKey(), peer.AllowedIPs, request.Tunnel)
+	if err == nil && request.PrivateKey != "" {
+		err = clientconfig.SetPrivateKey(config, request.PublicKey, request.PrivateKey)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, clientconfig.ErrInvalidConfig) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	switch request.Format {
+	case clientconfig.FormatJSON, "":
+		c.JSON(http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    config,
+		})
+	case clientconfig.FormatConf:
+		c.Header("Content-Disposition", `attachment; filename="`+s.config.WGInterface+`.conf"`)
+		c.String(http.StatusOK, clientconfig.WGQuick(config))
+	case clientconfig.FormatPNG:
+		png, err := clientconfig.QRCodePNG(config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, "image/png", png)
+	case clientconfig.FormatTerminal:
+		code, err := clientconfig.QRCodeTerminal(config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.String(http.StatusOK, code)
+	default:
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "format must be json, conf, png or terminal",
+		})
+	}
+}
+
+// getReconcileReport returns what the last peer reconciliation changed
+func (s *Server) getReconcileReport(c *gin.Context) {
+	report := s.wireguard.LastReconcile()
+	if report == nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "Peers have not been reconciled",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    report,
 	})
 }
 
@@ -239,7 +620,7 @@ func (s *Server) removePeer(c *gin.Context) {
 	}
 
 	// Broadcast to WebSocket clients
-	s.broadcastWebSocket(types.WebSocketMessage{
+	s.Broadcast(types.WebSocketMessage{
 		Type: "peer_removed",
 		Payload: map[string]interface{}{
 			"publicKey": publicKey,
@@ -265,9 +646,15 @@ func (s *Server) getPeer(c *gin.Context) {
 		return
 	}
 
+	// Report the peer with its data quota
+	view := *peer
+	if quota, exists := s.wireguard.Quota(publicKey); exists {
+		view.Quota = quota
+	}
+
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
-		Data:    peer,
+		Data:    view,
 	})
 }
 
@@ -318,11 +705,14 @@ func (s *Server) createPaymentStream(c *gin.Context) {
 		return
 	}
 
-	streamID, err := s.blockchain.CreatePaymentStream(request.Recipient, amount, request.Duration)
+	streamID, txHash, err := s.blockchain.CreatePaymentStream(request.Recipient, amount, request.Duration)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+		c.JSON(transactionErrorStatus(err), types.APIResponse{
 			Success: false,
-			Error:   err.Error(),
+			Data: map[string]interface{}{
+				"txHash": txHash,
+			},
+			Error: err.Error(),
 		})
 		return
 	}
@@ -331,6 +721,7 @@ func (s *Server) createPaymentStream(c *gin.Context) {
 		Success: true,
 		Data: map[string]interface{}{
 			"streamId": streamID,
+			"txHash":   txHash,
 		},
 	})
 }
@@ -340,6 +731,20 @@ func (s *Server) getStream(c *gin.Context) {
 	streamID := c.Param("streamId")
 
 	stream, err := s.blockchain.GetStream(streamID)
+	if errors.Is(err, blockchain.ErrInvalidStreamID) {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, blockchain.ErrStreamNotFound) {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.APIResponse{
 			Success: false,
@@ -378,10 +783,14 @@ func (s *Server) withdrawFromStream(c *gin.Context) {
 		return
 	}
 
-	if err := s.blockchain.WithdrawFromStream(request.StreamID, amount); err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+	txHash, err := s.blockchain.WithdrawFromStream(request.StreamID, amount)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), types.APIResponse{
 			Success: false,
-			Error:   err.Error(),
+			Data: map[string]interface{}{
+				"txHash": txHash,
+			},
+			Error: err.Error(),
 		})
 		return
 	}
@@ -389,45 +798,540 @@ func (s *Server) withdrawFromStream(c *gin.Context) {
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
 		Message: "Withdrawal successful",
+		Data: map[string]interface{}{
+			"txHash": txHash,
+		},
 	})
 }
 
-// getBandwidthStats returns bandwidth statistics
-func (s *Server) getBandwidthStats(c *gin.Context) {
-	totalRx, totalTx := s.wireguard.GetTotalBandwidth()
+// createSession connects a client paying through a payment stream
+func (s *Server) createSession(c *gin.Context) {
+	var request struct {
+		PublicKey string `json:"publicKey"`
+		Payer     string `json:"payer"`
+		StreamID  string `json:"streamId"`
+		Expiry    int64  `json:"expiry"`
+		Signature string `json:"signature"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	sess, config, err := s.sessions.Create(request.PublicKey, request.Payer, request.StreamID, request.Expiry, request.Signature)
+	if err != nil {
+		c.JSON(sessionErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
+		Message: "Session created",
 		Data: map[string]interface{}{
-			"totalRx": totalRx,
-			"totalTx": totalTx,
-			"total":   totalRx + totalTx,
+			"session": sess,
+			"config":  config,
 		},
 	})
 }
 
-// getPeerStats returns peer statistics
-func (s *Server) getPeerStats(c *gin.Context) {
+// getSessions returns every open session, or the one of the peer in the query
+func (s *Server) getSessions(c *gin.Context) {
+	if peer := c.Query("peer"); peer != "" {
+		sess, err := s.sessions.Session(peer)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    sess,
+		})
+		return
+	}
+
+	sessions, err := s.sessions.Sessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+// closeSession ends the session of the peer in the query, signed by its payer
+func (s *Server) closeSession(c *gin.Context) {
+	expiry, err := strconv.ParseInt(c.Query("expiry"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "expiry must be a Unix time",
+		})
+		return
+	}
+
+	if err := s.sessions.Close(c.Query("peer"), expiry, c.Query("signature")); err != nil {
+		c.JSON(sessionErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Session closed",
+	})
+}
+
+// getTicketDomain returns the EIP-712 domain and types clients sign tickets with
+func (s *Server) getTicketDomain(c *gin.Context) {
+	typedData, err := s.tickets.TypedData(&types.PaymentTicket{
+		Node:   s.blockchain.GetWalletAddress(),
+		Peer:   s.wireguard.GetPublicKey(),
+		Amount: "0",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"domain":      typedData.Domain,
+			"types":       typedData.Types,
+			"primaryType": typedData.PrimaryType,
+		},
+	})
+}
+
+// submitTicket verifies and stores a signed payment ticket
+func (s *Server) submitTicket(c *gin.Context) {
+	var ticket types.PaymentTicket
+	if err := c.ShouldBindJSON(&ticket); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	record, err := s.tickets.Submit(ticket)
+	if err != nil {
+		c.JSON(ticketErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Ticket accepted",
+		Data:    record,
+	})
+}
+
+// getTickets returns the latest ticket of every peer, or of the peer in the query
+func (s *Server) getTickets(c *gin.Context) {
+	if peer := c.Query("peer"); peer != "" {
+		record, err := s.tickets.Latest(peer)
+		if err != nil {
+			c.JSON(ticketErrorStatus(err), types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    record,
+		})
+		return
+	}
+
+	records, err := s.tickets.Records()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    records,
+	})
+}
+
+// getTicketSettlements exports unsettled tickets for NodeRegistry.processPayment
+func (s *Server) getTicketSettlements(c *gin.Context) {
+	settlements, err := s.tickets.Settlements()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    settlements,
+	})
+}
+
+// markTicketSettled records that an exported settlement was processed on-chain
+func (s *Server) markTicketSettled(c *gin.Context) {
+	var request struct {
+		Peer      string `json:"peer"`
+		Timestamp uint64 `json:"timestamp"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	record, err := s.tickets.MarkSettled(request.Peer, request.Timestamp)
+	if err != nil {
+		c.JSON(ticketErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Ticket marked as settled",
+		Data:    record,
+	})
+}
+
+// getBillingAccounts returns every billing account, or the one of the peer in the query
+func (s *Server) getBillingAccounts(c *gin.Context) {
+	if peer := c.Query("peer"); peer != "" {
+		account, err := s.billing.Account(peer)
+		if err != nil {
+			c.JSON(billingErrorStatus(err), types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    account,
+		})
+		return
+	}
+
+	accounts, err := s.billing.Accounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    accounts,
+	})
+}
+
+// attachBillingAccount links a peer to the payer and stream paying for it
+func (s *Server) attachBillingAccount(c *gin.Context) {
+	var request struct {
+		PublicKey string `json:"publicKey"`
+		Payer     string `json:"payer"`
+		StreamID  string `json:"streamId"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	account, err := s.billing.Attach(request.PublicKey, request.Payer, request.StreamID)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Billing account attached",
+		Data:    account,
+	})
+}
+
+// detachBillingAccount stops billing the peer in the query
+func (s *Server) detachBillingAccount(c *gin.Context) {
+	if err := s.billing.Detach(c.Query("peer")); err != nil {
+		c.JSON(billingErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Billing account detached",
+	})
+}
+
+// getBillingUsage returns the recent bandwidth samples of the peer in the query
+func (s *Server) getBillingUsage(c *gin.Context) {
+	usage, err := s.billing.Usage(c.Query("peer"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    usage,
+	})
+}
+
+// getBandwidthStats returns bandwidth statistics
+func (s *Server) getBandwidthStats(c *gin.Context) {
+	totalRx, totalTx := s.wireguard.GetTotalBandwidth()
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"totalRx": totalRx,
+			"totalTx": totalTx,
+			"total":   totalRx + totalTx,
+		},
+	})
+}
+
+// getPeerStats returns peer statistics
+func (s *Server) getPeerStats(c *gin.Context) {
 	peers := s.wireguard.GetPeers()
 	connectedCount := s.wireguard.GetConnectedPeersCount()
 
+	states := map[string]int{
+		wireguard.PeerPending:   0,
+		wireguard.PeerConnected: 0,
+		wireguard.PeerIdle:      0,
+		wireguard.PeerStale:     0,
+	}
+	for _, peer := range peers {
+		states[peer.State]++
+	}
+
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
 			"totalPeers":        len(peers),
 			"connectedPeers":    connectedCount,
 			"disconnectedPeers": len(peers) - connectedCount,
+			"peerStates":        states,
+			"addressPool":       s.wireguard.AddressPool().Stats(),
+			"nodeDailyUsage":    s.wireguard.NodeUsage(),
+		},
+	})
+}
+
+// getAddressStats returns the tunnel address pool utilisation and leases
+func (s *Server) getAddressStats(c *gin.Context) {
+	pool := s.wireguard.AddressPool()
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"pool":   pool.Stats(),
+			"leases": pool.Leases(),
+		},
+	})
+}
+
+// getShaping returns the shaping tiers and the rate limit of every peer, or
+// of the peer in the query
+func (s *Server) getShaping(c *gin.Context) {
+	if s.shaper == nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   "Traffic shaping is disabled",
+		})
+		return
+	}
+
+	if peer := c.Query("peer"); peer != "" {
+		limit, err := s.shaper.Peer(peer)
+		if err != nil {
+			c.JSON(shapingErrorStatus(err), types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    limit,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"tiers": s.shaper.Tiers(),
+			"peers": s.shaper.Peers(),
 		},
 	})
 }
 
+// setShaping moves a peer to a tier, or gives it custom rates when down or
+// up is set. An empty tier without rates puts the peer back on the default.
+func (s *Server) setShaping(c *gin.Context) {
+	if s.shaper == nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   "Traffic shaping is disabled",
+		})
+		return
+	}
+
+	var request struct {
+		PublicKey string  `json:"publicKey"`
+		Tier      string  `json:"tier"`
+		Down      *uint64 `json:"down"` // bytes per second, 0 is unlimited
+		Up        *uint64 `json:"up"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	var limit *types.PeerShaping
+	var err error
+	if request.Down != nil || request.Up != nil {
+		if request.Tier != "" {
+			c.JSON(http.StatusBadRequest, types.APIResponse{
+				Success: false,
+				Error:   "Set either a tier or rates, not both",
+			})
+			return
+		}
+
+		var down, up uint64
+		if request.Down != nil {
+			down = *request.Down
+		}
+		if request.Up != nil {
+			up = *request.Up
+		}
+		limit, err = s.shaper.SetRates(request.PublicKey, down, up)
+	} else {
+		limit, err = s.shaper.SetTier(request.PublicKey, request.Tier)
+	}
+	if err != nil {
+		c.JSON(shapingErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Shaping updated",
+		Data:    limit,
+	})
+}
+
+// getFirewallStatus returns the exit firewall rules in place
+func (s *Server) getFirewallStatus(c *gin.Context) {
+	if s.firewall == nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   "Firewall management is disabled",
+		})
+		return
+	}
+
+	status, err := s.firewall.Status()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
 // healthCheck returns health status
 func (s *Server) healthCheck(c *gin.Context) {
+	rpcStatus := s.blockchain.RPCPool().Status()
+
+	status := "degraded"
+	for _, endpoint := range rpcStatus {
+		if endpoint.Healthy {
+			status = "healthy"
+			break
+		}
+	}
+
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"status":    "healthy",
+			"status":    status,
 			"timestamp": time.Now().Unix(),
+			"rpc":       rpcStatus,
+			"wireguard": s.wireguard.Backend(),
 		},
 	})
 }
@@ -441,8 +1345,9 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	}
 
 	// Add connection to the pool
+	client := &wsConnection{conn: conn}
 	s.wsConnectionsMux.Lock()
-	s.wsConnections[conn] = true
+	s.wsConnections[conn] = client
 	s.wsConnectionsMux.Unlock()
 
 	s.logger.Info("New WebSocket connection established")
@@ -462,7 +1367,7 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		Payload: status,
 	}
 
-	if err := conn.WriteJSON(message); err != nil {
+	if err := client.writeJSON(message); err != nil {
 		s.logger.Errorf("Failed to send initial status: %v", err)
 	}
 
@@ -477,7 +1382,7 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		// Handle different message types
 		switch message.Type {
 		case "ping":
-			conn.WriteJSON(types.WebSocketMessage{
+			client.writeJSON(types.WebSocketMessage{
 				Type: "pong",
 			})
 		}
@@ -493,16 +1398,88 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	s.logger.Info("WebSocket connection closed")
 }
 
-// broadcastWebSocket broadcasts a message to all WebSocket clients
-func (s *Server) broadcastWebSocket(message types.WebSocketMessage) {
-	s.wsConnectionsMux.RLock()
-	defer s.wsConnectionsMux.RUnlock()
+// Broadcast sends a message to all WebSocket clients
+func (s *Server) Broadcast(message types.WebSocketMessage) {
+	s.wsConnectionsMux.Lock()
+	defer s.wsConnectionsMux.Unlock()
 
-	for conn := range s.wsConnections {
-		if err := conn.WriteJSON(message); err != nil {
+	for conn, client := range s.wsConnections {
+		if err := client.writeJSON(message); err != nil {
 			s.logger.Errorf("Failed to send WebSocket message: %v", err)
 			conn.Close()
 			delete(s.wsConnections, conn)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// billingErrorStatus maps a billing error to an HTTP status code
+func billingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, billing.ErrInvalidAccount), errors.Is(err, blockchain.ErrInvalidStreamID):
+		return http.StatusBadRequest
+	case errors.Is(err, billing.ErrAccountNotFound), errors.Is(err, blockchain.ErrStreamNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// sessionErrorStatus maps a session error to an HTTP status code
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, session.ErrInvalidSession), errors.Is(err, session.ErrInvalidSignature), errors.Is(err, blockchain.ErrInvalidStreamID):
+		return http.StatusBadRequest
+	case errors.Is(err, session.ErrSessionNotFound), errors.Is(err, blockchain.ErrStreamNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, session.ErrSessionExists):
+		return http.StatusConflict
+	}
+	return peerErrorStatus(err)
+}
+
+// peerErrorStatus maps an error adding a peer to an HTTP status code
+func peerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ipam.ErrInvalidAddress):
+		return http.StatusBadRequest
+	case errors.Is(err, ipam.ErrAddressInUse):
+		return http.StatusConflict
+	case errors.Is(err, ipam.ErrPoolExhausted), errors.Is(err, wireguard.ErrNotAcceptingPeers), errors.Is(err, wireguard.ErrNodeQuotaExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// shapingErrorStatus maps a traffic shaping error to an HTTP status code
+func shapingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, shaping.ErrInvalidPeer), errors.Is(err, shaping.ErrUnknownTier):
+		return http.StatusBadRequest
+	case errors.Is(err, shaping.ErrPeerNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ticketErrorStatus maps a ticket error to an HTTP status code
+func ticketErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, tickets.ErrInvalidTicket), errors.Is(err, tickets.ErrInvalidSignature):
+		return http.StatusBadRequest
+	case errors.Is(err, tickets.ErrUnknownPeer), errors.Is(err, tickets.ErrTicketNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tickets.ErrNoPayer):
+		return http.StatusForbidden
+	case errors.Is(err, tickets.ErrStaleTicket):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// transactionErrorStatus maps a blockchain transaction error to an HTTP status code
+func transactionErrorStatus(err error) int {
+	var revertErr *blockchain.RevertError
+	if errors.As(err, &revertErr) || errors.Is(err, blockchain.ErrInvalidStreamID) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,473 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+	"dvpn-node/internal/wireguard"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Account statuses
+const (
+	StatusOK        = "ok"
+	StatusUnderpaid = "underpaid"
+	StatusThrottled = "throttled"
+	StatusRemoved   = "removed"
+)
+
+// Underpayment actions
+const (
+	ActionWarn     = "warn"
+	ActionThrottle = "throttle"
+	ActionRemove   = "remove"
+)
+
+const (
+	accountsBucket = "billing_accounts"
+	usageBucket    = "billing_usage"
+
+	// usageRetention is how long bandwidth usage samples are kept
+	usageRetention = 24 * time.Hour
+)
+
+// bytesPerGB is the unit BILLING_PRICE_PER_GB is quoted in
+var bytesPerGB = big.NewInt(1_000_000_000)
+
+var (
+	// ErrAccountNotFound is returned when a peer has no billing account
+	ErrAccountNotFound = errors.New("billing account not found")
+
+	// ErrInvalidAccount is returned when a peer cannot be linked to a stream
+	ErrInvalidAccount = errors.New("invalid billing account")
+)
+
+// Throttler limits the bandwidth of a peer. Each source lifts only its own
+// throttle.
+type Throttler interface {
+	Throttle(publicKey, source string, bytesPerSecond uint64) error
+	Unthrottle(publicKey, source string) error
+}
+
+// throttleSource identifies the throttles billing puts on underpaid peers
+const throttleSource = "billing"
+
+// Engine meters WireGuard peers and checks that their payment streams keep
+// up with the cost of the traffic they use. Each peer is linked to a payer
+// and a PaymentHub stream; usage is sampled from the interface byte
+// counters, priced per GB and compared to the amount the stream has
+// released so far. Peers that stay underpaid past the grace period are
+// warned about, throttled or removed.
+type Engine struct {
+	blockchain   *blockchain.BlockchainService
+	wireguard    *wireguard.WireGuardService
+	store        *store.Store
+	logger       *logrus.Logger
+	interval     time.Duration
+	pricePerGB   *big.Int
+	gracePeriod  time.Duration
+	action       string
+	throttleRate uint64
+	throttler    Throttler
+	notify       func(types.WebSocketMessage)
+
+	mu sync.Mutex
+}
+
+// NewEngine creates a billing engine from the node configuration
+func NewEngine(config *types.NodeConfig, blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService, db *store.Store, logger *logrus.Logger) (*Engine, error) {
+	pricePerGB, ok := new(big.Int).SetString(config.BillingPricePerGB, 10)
+	if !ok || pricePerGB.Sign() < 0 {
+		return nil, fmt.Errorf("invalid BILLING_PRICE_PER_GB: %s", config.BillingPricePerGB)
+	}
+
+	switch config.BillingUnderpayAction {
+	case ActionWarn, ActionThrottle, ActionRemove:
+	default:
+		return nil, fmt.Errorf("invalid BILLING_UNDERPAY_ACTION: %s", config.BillingUnderpayAction)
+	}
+
+	return &Engine{
+		blockchain:   blockchain,
+		wireguard:    wireguard,
+		store:        db,
+		logger:       logger,
+		interval:     config.BillingInterval,
+		pricePerGB:   pricePerGB,
+		gracePeriod:  config.BillingGracePeriod,
+		action:       config.BillingUnderpayAction,
+		throttleRate: config.BillingThrottleRate,
+		notify:       func(types.WebSocketMessage) {},
+	}, nil
+}
+
+// SetNotifier sets the function used to report billing events to WebSocket clients
+func (e *Engine) SetNotifier(notify func(types.WebSocketMessage)) {
+	e.notify = notify
+}
+
+// SetThrottler sets the bandwidth limiter used by the throttle action
+func (e *Engine) SetThrottler(throttler Throttler) {
+	e.throttler = throttler
+}
+
+// Attach links a peer to the payer and stream paying for it. The stream must
+// be an active stream from payer to the node wallet.
+func (e *Engine) Attach(publicKey, payer, streamID string) (*types.BillingAccount, error) {
+	if _, exists := e.wireguard.GetPeer(publicKey); !exists {
+		return nil, fmt.Errorf("%w: peer %s not found", ErrInvalidAccount, publicKey)
+	}
+
+	stream, err := e.blockchain.GetStream(streamID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(stream.Recipient, e.blockchain.GetWalletAddress()) {
+		return nil, fmt.Errorf("%w: stream %s does not pay this node", ErrInvalidAccount, streamID)
+	}
+	if !strings.EqualFold(stream.Sender, payer) {
+		return nil, fmt.Errorf("%w: stream %s is not from %s", ErrInvalidAccount, streamID, payer)
+	}
+	if !stream.IsActive {
+		return nil, fmt.Errorf("%w: stream %s is not active", ErrInvalidAccount, streamID)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now().Unix()
+	account := types.BillingAccount{
+		PeerPublicKey: publicKey,
+		Payer:         stream.Sender,
+		StreamID:      stream.StreamID,
+		Prepaid:       stream.Amount,
+		Cost:          "0",
+		Streamed:      "0",
+		Status:        StatusOK,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// Keep metering from the current counters when the stream is replaced
+	var existing types.BillingAccount
+	found, err := e.store.Get(accountsBucket, publicKey, &existing)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		account.BytesRx, account.BytesTx = existing.BytesRx, existing.BytesTx
+		account.CounterRx, account.CounterTx = existing.CounterRx, existing.CounterTx
+		account.Generation = existing.Generation
+		account.Cost = existing.Cost
+		account.CreatedAt = existing.CreatedAt
+	} else if peer, exists := e.wireguard.GetPeer(publicKey); exists {
+		// Traffic from before the account existed is not billed
+		account.CounterRx, account.CounterTx = peer.BytesRx, peer.BytesTx
+		account.Generation = peer.Generation
+	}
+
+	if err := e.store.Put(accountsBucket, publicKey, account); err != nil {
+		return nil, err
+	}
+
+	e.logger.Infof("Billing peer %s to %s via stream %s", publicKey, account.Payer, account.StreamID)
+	return &account, nil
+}
+
+// Detach stops billing a peer
+func (e *Engine) Detach(publicKey string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.account(publicKey); err != nil {
+		return err
+	}
+	return e.store.Delete(accountsBucket, publicKey)
+}
+
+// Account returns the billing account of a peer
+func (e *Engine) Account(publicKey string) (*types.BillingAccount, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.account(publicKey)
+}
+
+// PrepaidBytes returns how many bytes the stream of a peer pays for at
+// BILLING_PRICE_PER_GB
+func (e *Engine) PrepaidBytes(publicKey string) (int64, bool) {
+	account, err := e.Account(publicKey)
+	if err != nil || e.pricePerGB.Sign() == 0 {
+		return 0, false
+	}
+
+	prepaid, ok := new(big.Int).SetString(account.Prepaid, 10)
+	if !ok {
+		return 0, false
+	}
+
+	bytes := new(big.Int).Mul(prepaid, bytesPerGB)
+	bytes.Quo(bytes, e.pricePerGB)
+	if !bytes.IsInt64() {
+		return 0, false
+	}
+	return bytes.Int64(), true
+}
+
+// Accounts returns every billing account
+func (e *Engine) Accounts() ([]types.BillingAccount, error) {
+	var accounts []types.BillingAccount
+	err := e.store.ForEach(accountsBucket, func(_ string, value []byte) error {
+		var account types.BillingAccount
+		if err := json.Unmarshal(value, &account); err != nil {
+			return err
+		}
+		accounts = append(accounts, account)
+		return nil
+	})
+	return accounts, err
+}
+
+// Usage returns the bandwidth samples recorded for a peer, oldest first
+func (e *Engine) Usage(publicKey string) ([]types.BandwidthUsage, error) {
+	var usage []types.BandwidthUsage
+	err := e.store.ForEach(usageBucket, func(key string, value []byte) error {
+		if !strings.HasPrefix(key, publicKey+"/") {
+			return nil
+		}
+		var sample types.BandwidthUsage
+		if err := json.Unmarshal(value, &sample); err != nil {
+			return err
+		}
+		usage = append(usage, sample)
+		return nil
+	})
+	return usage, err
+}
+
+// Run meters every billed peer each interval until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.wireguard.UpdatePeerStats(); err != nil {
+			e.logger.Errorf("Failed to update peer stats for billing: %v", err)
+			continue
+		}
+
+		accounts, err := e.Accounts()
+		if err != nil {
+			e.logger.Errorf("Failed to load billing accounts: %v", err)
+			continue
+		}
+
+		for _, account := range accounts {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := e.bill(account.PeerPublicKey); err != nil {
+				e.logger.Errorf("Failed to bill peer %s: %v", account.PeerPublicKey, err)
+			}
+		}
+
+		e.pruneUsage()
+	}
+}
+
+// bill samples the usage of one peer and checks it against its stream
+func (e *Engine) bill(publicKey string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	account, err := e.account(publicKey)
+	if err != nil {
+		return err
+	}
+	if account.Status == StatusRemoved {
+		return nil
+	}
+
+	peer, exists := e.wireguard.GetPeer(publicKey)
+	if !exists {
+		return nil
+	}
+
+	// Counters restart from zero only when the peer is re-created on the
+	// device. Any other drop is a stale reading and bills nothing.
+	if peer.Generation != account.Generation {
+		account.Generation = peer.Generation
+		account.CounterRx, account.CounterTx = 0, 0
+	}
+	deltaRx := max(peer.BytesRx-account.CounterRx, 0)
+	deltaTx := max(peer.BytesTx-account.CounterTx, 0)
+
+	now := time.Now()
+	account.BytesRx += deltaRx
+	account.BytesTx += deltaTx
+	account.CounterRx, account.CounterTx = max(account.CounterRx, peer.BytesRx), max(account.CounterTx, peer.BytesTx)
+	account.UpdatedAt = now.Unix()
+
+	if deltaRx > 0 || deltaTx > 0 {
+		sample := types.BandwidthUsage{
+			PeerPublicKey: publicKey,
+			BytesRx:       deltaRx,
+			BytesTx:       deltaTx,
+			Timestamp:     now,
+		}
+		if err := e.store.Put(usageBucket, fmt.Sprintf("%s/%020d", publicKey, now.UnixNano()), sample); err != nil {
+			return err
+		}
+	}
+
+	cost := new(big.Int).Mul(big.NewInt(account.BytesRx+account.BytesTx), e.pricePerGB)
+	cost.Div(cost, bytesPerGB)
+	account.Cost = cost.String()
+
+	stream, err := e.blockchain.GetStream(account.StreamID)
+	if err != nil {
+		// Keep the metered usage even if the stream cannot be read right now
+		if saveErr := e.store.Put(accountsBucket, publicKey, account); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	withdrawn, _ := new(big.Int).SetString(stream.Withdrawn, 10)
+	available, _ := new(big.Int).SetString(stream.Available, 10)
+	streamed := new(big.Int).Add(withdrawn, available)
+	account.Streamed = streamed.String()
+
+	if cost.Cmp(streamed) <= 0 {
+		e.settle(account)
+	} else {
+		e.underpaid(account, now)
+	}
+
+	return e.store.Put(accountsBucket, publicKey, account)
+}
+
+// settle clears the underpaid state of an account that has caught up
+func (e *Engine) settle(account *types.BillingAccount) {
+	if account.Status == StatusOK {
+		return
+	}
+
+	if account.Status == StatusThrottled && e.throttler != nil {
+		if err := e.throttler.Unthrottle(account.PeerPublicKey, throttleSource); err != nil {
+			e.logger.Errorf("Failed to lift throttle on peer %s: %v", account.PeerPublicKey, err)
+			return
+		}
+	}
+
+	e.logger.Infof("Peer %s is paid up again", account.PeerPublicKey)
+	account.Status = StatusOK
+	account.UnderpaidSince = 0
+}
+
+// underpaid applies the underpayment action once the grace period has passed
+func (e *Engine) underpaid(account *types.BillingAccount, now time.Time) {
+	if account.UnderpaidSince == 0 {
+		account.UnderpaidSince = now.Unix()
+	}
+	if account.Status != StatusOK || now.Sub(time.Unix(account.UnderpaidSince, 0)) < e.gracePeriod {
+		return
+	}
+
+	action := e.action
+	if action == ActionThrottle && e.throttler == nil {
+		e.logger.Warnf("No bandwidth limiter available, removing peer %s instead of throttling", account.PeerPublicKey)
+		action = ActionRemove
+	}
+
+	e.logger.Warnf("Peer %s is underpaid: cost %s, streamed %s (action: %s)",
+		account.PeerPublicKey, account.Cost, account.Streamed, action)
+
+	switch action {
+	case ActionWarn:
+		account.Status = StatusUnderpaid
+
+	case ActionThrottle:
+		if err := e.throttler.Throttle(account.PeerPublicKey, throttleSource, e.throttleRate); err != nil {
+			e.logger.Errorf("Failed to throttle peer %s: %v", account.PeerPublicKey, err)
+			return
+		}
+		account.Status = StatusThrottled
+
+	case ActionRemove:
+		if err := e.wireguard.RemovePeer(account.PeerPublicKey); err != nil {
+			e.logger.Errorf("Failed to remove underpaid peer %s: %v", account.PeerPublicKey, err)
+			return
+		}
+		account.Status = StatusRemoved
+
+		e.notify(types.WebSocketMessage{
+			Type: "peer_removed",
+			Payload: map[string]interface{}{
+				"publicKey": account.PeerPublicKey,
+				"reason":    "underpaid",
+			},
+		})
+	}
+
+	e.notify(types.WebSocketMessage{
+		Type:    "billing_underpaid",
+		Payload: account,
+	})
+}
+
+// account loads a billing account; e.mu must be held
+func (e *Engine) account(publicKey string) (*types.BillingAccount, error) {
+	var account types.BillingAccount
+	found, err := e.store.Get(accountsBucket, publicKey, &account)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, publicKey)
+	}
+	return &account, nil
+}
+
+// pruneUsage deletes usage samples older than the retention period
+func (e *Engine) pruneUsage() {
+	cutoff := time.Now().Add(-usageRetention)
+
+	var expired []string
+	err := e.store.ForEach(usageBucket, func(key string, value []byte) error {
+		var sample types.BandwidthUsage
+		if err := json.Unmarshal(value, &sample); err != nil {
+			return err
+		}
+		if sample.Timestamp.Before(cutoff) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		e.logger.Errorf("Failed to load usage samples: %v", err)
+		return
+	}
+
+	for _, key := range expired {
+		if err := e.store.Delete(usageBucket, key); err != nil {
+			e.logger.Errorf("Failed to prune usage sample: %v", err)
+		}
+	}
+}
@@ -2,174 +2,422 @@ package blockchain
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
-	"strings"
 
+	"dvpn-node/internal/blockchain/contracts"
+	"dvpn-node/internal/store"
 	"dvpn-node/internal/types"
 
-	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/sirupsen/logrus"
 )
 
 // BlockchainService handles all blockchain interactions
 type BlockchainService struct {
-	client           *ethclient.Client
-	privateKey       *ecdsa.PrivateKey
+	client           *RPCPool
+	signer           Signer
 	walletAddress    common.Address
+	chainID          *big.Int
 	tokenAddress     common.Address
 	nodeRegistryAddr common.Address
 	paymentHubAddr   common.Address
+	token            *contracts.DVPNToken
+	nodeRegistry     *contracts.NodeRegistry
+	paymentHub       *contracts.PaymentHub
+	txManager        *TxManager
 	logger           *logrus.Logger
 }
 
+var (
+	// ErrNodeNotRegistered is returned when an address has no entry in the NodeRegistry
+	ErrNodeNotRegistered = errors.New("node not registered")
+
+	// ErrStreamNotFound is returned when a stream ID does not exist in the PaymentHub
+	ErrStreamNotFound = errors.New("stream not found")
+
+	// ErrInvalidStreamID is returned when a stream ID is not a 32-byte hex string
+	ErrInvalidStreamID = errors.New("invalid stream ID")
+
+	// ErrNodeInactive is returned when the node has already been unregistered
+	ErrNodeInactive = errors.New("node is not active")
+
+	// ErrStakeTopUpUnsupported is returned by TopUpStake because NodeRegistry
+	// only accepts a stake in registerNode and has no function to add to it
+	ErrStakeTopUpUnsupported = errors.New("NodeRegistry does not support adding stake after registration")
+)
 
 // NewBlockchainService creates a new blockchain service
-func NewBlockchainService(config *types.NodeConfig, logger *logrus.Logger) (*BlockchainService, error) {
-	client, err := ethclient.Dial(config.RPCURL)
+func NewBlockchainService(config *types.NodeConfig, db *store.Store, logger *logrus.Logger) (*BlockchainService, error) {
+	client, err := NewRPCPool(config, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
+		return nil, err
 	}
 
-	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+	signer, err := newSigner(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	walletAddress := signer.Address()
+
+	chainID, err := client.ChainID(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %w", err)
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("failed to get public key")
+	txManager, err := NewTxManager(config, client, db, walletAddress, signerFn(signer, chainID), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction manager: %w", err)
+	}
+
+	tokenAddress := common.HexToAddress(config.TokenAddress)
+	nodeRegistryAddr := common.HexToAddress(config.NodeRegistryAddr)
+	paymentHubAddr := common.HexToAddress(config.PaymentHubAddr)
+
+	token, err := contracts.NewDVPNToken(tokenAddress, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind token contract: %w", err)
+	}
+
+	nodeRegistry, err := contracts.NewNodeRegistry(nodeRegistryAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind NodeRegistry contract: %w", err)
 	}
 
-	walletAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	paymentHub, err := contracts.NewPaymentHub(paymentHubAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind PaymentHub contract: %w", err)
+	}
 
 	return &BlockchainService{
 		client:           client,
-		privateKey:       privateKey,
+		signer:           signer,
 		walletAddress:    walletAddress,
-		tokenAddress:     common.HexToAddress(config.TokenAddress),
-		nodeRegistryAddr: common.HexToAddress(config.NodeRegistryAddr),
-		paymentHubAddr:   common.HexToAddress(config.PaymentHubAddr),
+		chainID:          chainID,
+		tokenAddress:     tokenAddress,
+		nodeRegistryAddr: nodeRegistryAddr,
+		paymentHubAddr:   paymentHubAddr,
+		token:            token,
+		nodeRegistry:     nodeRegistry,
+		paymentHub:       paymentHub,
+		txManager:        txManager,
 		logger:           logger,
 	}, nil
 }
 
 // GetNodeInfo retrieves node information from the registry
 func (b *BlockchainService) GetNodeInfo(nodeAddress string) (*types.NodeInfo, error) {
-	// Simplified ABI for getNode function
-	data := []byte("getNode(address)")
-	methodID := crypto.Keccak256(data)[:4]
-
 	addr := common.HexToAddress(nodeAddress)
-	input := append(methodID, addr.Bytes()...)
 
-	msg := ethereum.CallMsg{
-		To:   &b.nodeRegistryAddr,
-		Data: input,
+	registered, err := b.IsRegistered(nodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !registered {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotRegistered, addr.Hex())
 	}
 
-	_, err := b.client.CallContract(context.Background(), msg, nil)
+	node, err := b.nodeRegistry.GetNode(b.callOpts(), addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call contract: %w", err)
+		return nil, fmt.Errorf("failed to get node: %w", err)
 	}
 
-	// Parse the result (simplified - in production you'd use proper ABI parsing)
-	// For now, return a mock response
 	return &types.NodeInfo{
-		Owner:                  b.walletAddress,
-		Metadata:               "Toronto, Canada",
-		Stake:                  "1000000000000000000000", // 1000 tokens
-		Reputation:             100,
-		LastActive:             uint64(0),
-		IsActive:               true,
-		TotalBandwidthProvided: 0,
-		TotalEarnings:          "0",
+		Owner:                  node.Owner,
+		Metadata:               node.Metadata,
+		Stake:                  node.Stake.String(),
+		Reputation:             node.Reputation.Uint64(),
+		LastActive:             node.LastActive.Uint64(),
+		IsActive:               node.IsActive,
+		TotalBandwidthProvided: node.TotalBandwidthProvided.Uint64(),
+		TotalEarnings:          node.TotalEarnings.String(),
 	}, nil
 }
 
-// RegisterNode registers the node in the registry
-func (b *BlockchainService) RegisterNode(metadata string, stake *big.Int) error {
+// IsRegistered checks whether an address has ever registered in the registry
+func (b *BlockchainService) IsRegistered(nodeAddress string) (bool, error) {
+	registered, err := b.nodeRegistry.IsRegistered(b.callOpts(), common.HexToAddress(nodeAddress))
+	if err != nil {
+		return false, fmt.Errorf("failed to check registration: %w", err)
+	}
+
+	return registered, nil
+}
+
+// GetActiveNodes returns the addresses of all active nodes in the registry
+func (b *BlockchainService) GetActiveNodes() ([]string, error) {
+	nodes, err := b.nodeRegistry.GetActiveNodes(b.callOpts())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active nodes: %w", err)
+	}
+
+	addresses := make([]string, len(nodes))
+	for i, node := range nodes {
+		addresses[i] = node.Hex()
+	}
+
+	return addresses, nil
+}
+
+// RegisterNode approves the stake and registers the node in the registry
+func (b *BlockchainService) RegisterNode(metadata string, stake *big.Int) (*types.RegistrationResult, error) {
 	b.logger.Info("Registering node in blockchain registry...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), txTimeout)
+	defer cancel()
+
+	result := &types.RegistrationResult{}
+
 	// First approve tokens
-	if err := b.approveTokens(b.nodeRegistryAddr, stake); err != nil {
-		return fmt.Errorf("failed to approve tokens: %w", err)
+	approveTxHash, err := b.approveTokens(ctx, b.nodeRegistryAddr, stake)
+	if err != nil {
+		return result, fmt.Errorf("failed to approve tokens: %w", err)
 	}
+	result.ApproveTxHash = approveTxHash
 
-	// Then register node (simplified - would use proper ABI in production)
-	b.logger.Info("Node registered successfully")
-	return nil
+	// Then register node
+	tx, err := b.txManager.Transact(ctx, "registerNode", func(opts *bind.TransactOpts) (*ethtypes.Transaction, error) {
+		return b.nodeRegistry.RegisterNode(opts, metadata, stake)
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to send registerNode transaction: %w", err)
+	}
+	result.RegisterTxHash = tx.Hash().Hex()
+
+	if _, err := b.txManager.Wait(ctx, tx); err != nil {
+		return result, fmt.Errorf("failed to register node: %w", err)
+	}
+
+	b.logger.Infof("Node registered successfully (tx: %s)", result.RegisterTxHash)
+	return result, nil
 }
 
-// GetTokenBalance gets the token balance for an address
-func (b *BlockchainService) GetTokenBalance(address string) (*big.Int, error) {
-	// Simplified balance check
-	data := []byte("balanceOf(address)")
-	methodID := crypto.Keccak256(data)[:4]
+// UnregisterNode deactivates the node in the registry, which refunds its
+// stake to the node wallet, and returns the transaction hash. The registry
+// does not allow an unregistered address to register again.
+func (b *BlockchainService) UnregisterNode() (string, error) {
+	b.logger.Info("Unregistering node from blockchain registry...")
+
+	stake, err := b.GetStake()
+	if err != nil {
+		return "", err
+	}
+	if !stake.IsActive {
+		return "", ErrNodeInactive
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), txTimeout)
+	defer cancel()
+
+	tx, err := b.txManager.Transact(ctx, "unregisterNode", func(opts *bind.TransactOpts) (*ethtypes.Transaction, error) {
+		return b.nodeRegistry.UnregisterNode(opts)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send unregisterNode transaction: %w", err)
+	}
 
-	addr := common.HexToAddress(address)
-	input := append(methodID, addr.Bytes()...)
+	if _, err := b.txManager.Wait(ctx, tx); err != nil {
+		return tx.Hash().Hex(), fmt.Errorf("failed to unregister node: %w", err)
+	}
 
-	msg := ethereum.CallMsg{
-		To:   &b.tokenAddress,
-		Data: input,
+	b.logger.Infof("Node unregistered, %s tokens of stake refunded (tx: %s)", stake.Stake, tx.Hash().Hex())
+	return tx.Hash().Hex(), nil
+}
+
+// GetStake returns the stake of the node wallet and the registry minimum
+func (b *BlockchainService) GetStake() (*types.StakeInfo, error) {
+	node, err := b.GetNodeInfo(b.walletAddress.Hex())
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := b.client.CallContract(context.Background(), msg, nil)
+	minStake, err := b.nodeRegistry.MinStake(b.callOpts())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minimum stake: %w", err)
+	}
+
+	return &types.StakeInfo{
+		Stake:    node.Stake,
+		MinStake: minStake.String(),
+		IsActive: node.IsActive,
+	}, nil
+}
+
+// TopUpStake would add amount to the node stake. The deployed NodeRegistry
+// has no such function, so this always returns ErrStakeTopUpUnsupported; the
+// only way to change the stake is to unregister and register a new wallet.
+func (b *BlockchainService) TopUpStake(amount *big.Int) (string, error) {
+	return "", ErrStakeTopUpUnsupported
+}
+
+// GetTokenBalance gets the token balance for an address
+func (b *BlockchainService) GetTokenBalance(address string) (*big.Int, error) {
+	balance, err := b.token.BalanceOf(b.callOpts(), common.HexToAddress(address))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
 
-	balance := new(big.Int).SetBytes(result)
 	return balance, nil
 }
 
-// CreatePaymentStream creates a payment stream
-func (b *BlockchainService) CreatePaymentStream(recipient string, amount *big.Int, duration uint64) (string, error) {
+// CreatePaymentStream approves amount and opens a stream from the node wallet
+// to recipient, returning the on-chain stream ID and the createStream tx hash
+func (b *BlockchainService) CreatePaymentStream(recipient string, amount *big.Int, duration uint64) (string, string, error) {
 	b.logger.Infof("Creating payment stream to %s for %s tokens", recipient, amount.String())
 
-	// Simplified stream creation
-	streamID := fmt.Sprintf("stream_%s_%d", recipient, duration)
-	b.logger.Infof("Payment stream created: %s", streamID)
+	if !common.IsHexAddress(recipient) {
+		return "", "", fmt.Errorf("invalid recipient address: %s", recipient)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), txTimeout)
+	defer cancel()
 
-	return streamID, nil
+	if _, err := b.approveTokens(ctx, b.paymentHubAddr, amount); err != nil {
+		return "", "", fmt.Errorf("failed to approve tokens: %w", err)
+	}
+
+	// A retry of the same stream reuses the pending transaction rather than
+	// locking the funds a second time
+	key := fmt.Sprintf("createStream:%s:%s:%d", common.HexToAddress(recipient).Hex(), amount.String(), duration)
+	tx, err := b.txManager.Transact(ctx, key, func(opts *bind.TransactOpts) (*ethtypes.Transaction, error) {
+		return b.paymentHub.CreateStream(opts, common.HexToAddress(recipient), amount, new(big.Int).SetUint64(duration))
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send createStream transaction: %w", err)
+	}
+
+	receipt, err := b.txManager.Wait(ctx, tx)
+	if err != nil {
+		return "", tx.Hash().Hex(), fmt.Errorf("failed to create stream: %w", err)
+	}
+
+	// The stream ID depends on the block timestamp, so recover it from the event
+	for _, log := range receipt.Logs {
+		if log.Address != b.paymentHubAddr {
+			continue
+		}
+		event, err := b.paymentHub.ParseStreamCreated(*log)
+		if err != nil {
+			continue
+		}
+
+		streamID := hexutil.Encode(event.StreamId[:])
+		b.logger.Infof("Payment stream created: %s", streamID)
+		return streamID, tx.Hash().Hex(), nil
+	}
+
+	return "", tx.Hash().Hex(), fmt.Errorf("no StreamCreated event in transaction %s", tx.Hash().Hex())
 }
 
 // GetStream gets payment stream information
 func (b *BlockchainService) GetStream(streamID string) (*types.PaymentStream, error) {
-	// Simplified stream retrieval
+	id, err := parseStreamID(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := b.paymentHub.GetStream(b.callOpts(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream: %w", err)
+	}
+	if stream.Sender == (common.Address{}) {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	available, err := b.paymentHub.GetAvailableAmount(b.callOpts(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available amount: %w", err)
+	}
+
 	return &types.PaymentStream{
-		StreamID:  streamID,
-		Sender:    b.walletAddress.Hex(),
-		Recipient: b.walletAddress.Hex(),
-		Amount:    "1000000000000000000000", // 1000 tokens
-		StartTime: uint64(0),
-		EndTime:   uint64(0),
-		Withdrawn: "0",
-		IsActive:  true,
+		StreamID:  hexutil.Encode(id[:]),
+		Sender:    stream.Sender.Hex(),
+		Recipient: stream.Recipient.Hex(),
+		Amount:    stream.Amount.String(),
+		StartTime: stream.StartTime.Uint64(),
+		EndTime:   stream.EndTime.Uint64(),
+		Withdrawn: stream.Withdrawn.String(),
+		Available: available.String(),
+		IsActive:  stream.IsActive,
 	}, nil
 }
 
-// WithdrawFromStream withdraws from a payment stream
-func (b *BlockchainService) WithdrawFromStream(streamID string, amount *big.Int) error {
+// WithdrawFromStream withdraws amount from a stream paying the node wallet,
+// returning the transaction hash
+func (b *BlockchainService) WithdrawFromStream(streamID string, amount *big.Int) (string, error) {
 	b.logger.Infof("Withdrawing %s tokens from stream %s", amount.String(), streamID)
 
-	// Simplified withdrawal
-	b.logger.Info("Withdrawal successful")
-	return nil
+	id, err := parseStreamID(streamID)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), txTimeout)
+	defer cancel()
+
+	tx, err := b.txManager.Transact(ctx, "withdraw:"+hexutil.Encode(id[:]), func(opts *bind.TransactOpts) (*ethtypes.Transaction, error) {
+		return b.paymentHub.WithdrawFromStream(opts, id, amount)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send withdrawFromStream transaction: %w", err)
+	}
+
+	if _, err := b.txManager.Wait(ctx, tx); err != nil {
+		return tx.Hash().Hex(), fmt.Errorf("failed to withdraw from stream: %w", err)
+	}
+
+	b.logger.Infof("Withdrawal successful (tx: %s)", tx.Hash().Hex())
+	return tx.Hash().Hex(), nil
 }
 
-// approveTokens approves tokens for spending
-func (b *BlockchainService) approveTokens(spender common.Address, amount *big.Int) error {
+// GetAvailableAmount returns the amount currently withdrawable from a stream
+func (b *BlockchainService) GetAvailableAmount(streamID string) (*big.Int, error) {
+	id, err := parseStreamID(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := b.paymentHub.GetAvailableAmount(b.callOpts(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available amount: %w", err)
+	}
+
+	return amount, nil
+}
+
+// approveTokens approves tokens for spending, returning the approval tx hash.
+// No transaction is sent if the existing allowance already covers amount.
+func (b *BlockchainService) approveTokens(ctx context.Context, spender common.Address, amount *big.Int) (string, error) {
+	allowance, err := b.token.Allowance(&bind.CallOpts{Context: ctx}, b.walletAddress, spender)
+	if err != nil {
+		return "", fmt.Errorf("failed to get allowance: %w", err)
+	}
+	if allowance.Cmp(amount) >= 0 {
+		b.logger.Infof("Existing allowance of %s tokens for %s is sufficient", allowance.String(), spender.Hex())
+		return "", nil
+	}
+
 	b.logger.Infof("Approving %s tokens for %s", amount.String(), spender.Hex())
 
-	// Simplified approval
-	return nil
+	// A pending approval of another amount must not stand in for this one
+	key := fmt.Sprintf("approve:%s:%s", spender.Hex(), amount.String())
+	tx, err := b.txManager.Transact(ctx, key, func(opts *bind.TransactOpts) (*ethtypes.Transaction, error) {
+		return b.token.Approve(opts, spender, amount)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send approve transaction: %w", err)
+	}
+
+	if _, err := b.txManager.Wait(ctx, tx); err != nil {
+		return tx.Hash().Hex(), err
+	}
+
+	return tx.Hash().Hex(), nil
 }
 
 // GetWalletAddress returns the wallet address
@@ -177,9 +425,63 @@ func (b *BlockchainService) GetWalletAddress() string {
 	return b.walletAddress.Hex()
 }
 
+// BlockNumber returns the number of the latest block
+func (b *BlockchainService) BlockNumber(ctx context.Context) (uint64, error) {
+	return b.client.BlockNumber(ctx)
+}
+
+// TxManager returns the transaction manager used for all node wallet transactions
+func (b *BlockchainService) TxManager() *TxManager {
+	return b.txManager
+}
+
+// callOpts returns the options used for read-only contract calls
+func (b *BlockchainService) callOpts() *bind.CallOpts {
+	return &bind.CallOpts{
+		From:    b.walletAddress,
+		Context: context.Background(),
+	}
+}
+
+// parseStreamID converts a hex-encoded stream ID into its bytes32 form
+func parseStreamID(streamID string) ([32]byte, error) {
+	var id [32]byte
+
+	raw, err := hexutil.Decode(streamID)
+	if err != nil || len(raw) != len(id) {
+		return id, fmt.Errorf("%w: %s", ErrInvalidStreamID, streamID)
+	}
+
+	copy(id[:], raw)
+	return id, nil
+}
+
+// SignMessage signs message with the node wallet (EIP-191)
+func (b *BlockchainService) SignMessage(message []byte) ([]byte, error) {
+	return b.signer.SignMessage(context.Background(), message)
+}
+
+// ChainID returns the chain ID of the connected network
+func (b *BlockchainService) ChainID() *big.Int {
+	return new(big.Int).Set(b.chainID)
+}
+
+// NodeRegistryAddress returns the address of the NodeRegistry contract
+func (b *BlockchainService) NodeRegistryAddress() string {
+	return b.nodeRegistryAddr.Hex()
+}
+
+// RPCPool returns the pool of RPC endpoints used by the service
+func (b *BlockchainService) RPCPool() *RPCPool {
+	return b.client
+}
+
 // Close closes the blockchain connection
 func (b *BlockchainService) Close() {
 	if b.client != nil {
 		b.client.Close()
 	}
+	if remote, ok := b.signer.(*RemoteSigner); ok {
+		remote.Close()
+	}
 }
@@ -0,0 +1,9 @@
+// Package contracts contains Go bindings for the dVPN smart contracts.
+//
+// The ABIs in abi/ mirror the Solidity sources in Contracts/src. After
+// changing a contract, update its ABI and run `go generate ./...`.
+package contracts
+
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi abi/NodeRegistry.abi --pkg contracts --type NodeRegistry --out noderegistry.go
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi abi/PaymentHub.abi --pkg contracts --type PaymentHub --out paymenthub.go
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi abi/dVPNToken.abi --pkg contracts --type DVPNToken --out dvpntoken.go
@@ -0,0 +1,463 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/blockchain/contracts"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventsBucket        = "events"
+	indexerBlocksBucket = "indexer_blocks"
+
+	indexerCursorKey = "indexer"
+
+	// subscriberBuffer is the number of events buffered per subscriber
+	subscriberBuffer = 1024
+)
+
+// indexerCursor records how far the indexer has progressed
+type indexerCursor struct {
+	Head      uint64 `json:"head"`      // last block whose events are stored
+	Confirmed uint64 `json:"confirmed"` // last block considered final
+}
+
+// indexedContract is a contract whose events are indexed
+type indexedContract struct {
+	name    string
+	address common.Address
+	abi     *abi.ABI
+}
+
+// subscription is a consumer registered through Subscribe
+type subscription struct {
+	names  map[string]bool
+	events chan types.ChainEvent
+}
+
+// Indexer backfills and follows NodeRegistry and PaymentHub events, storing
+// them with a confirmed-block cursor. Events in blocks that are not yet
+// confirmed are rolled back and re-published as removed when a reorg is
+// detected.
+type Indexer struct {
+	blockchain    *BlockchainService
+	store         *store.Store
+	logger        *logrus.Logger
+	contracts     []indexedContract
+	startBlock    uint64
+	confirmations uint64
+	pollInterval  time.Duration
+
+	subscribersMux sync.RWMutex
+	subscribers    map[*subscription]bool
+}
+
+// NewIndexer creates an indexer for the node's contracts
+func NewIndexer(config *types.NodeConfig, blockchain *BlockchainService, db *store.Store, logger *logrus.Logger) (*Indexer, error) {
+	registryABI, err := contracts.NodeRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load NodeRegistry ABI: %w", err)
+	}
+
+	hubABI, err := contracts.PaymentHubMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PaymentHub ABI: %w", err)
+	}
+
+	return &Indexer{
+		blockchain: blockchain,
+		store:      db,
+		logger:     logger,
+		contracts: []indexedContract{
+			{name: "NodeRegistry", address: blockchain.nodeRegistryAddr, abi: registryABI},
+			{name: "PaymentHub", address: blockchain.paymentHubAddr, abi: hubABI},
+		},
+		startBlock:    config.IndexerStartBlock,
+		confirmations: config.IndexerConfirmations,
+		pollInterval:  config.IndexerPollInterval,
+		subscribers:   make(map[*subscription]bool),
+	}, nil
+}
+
+// Subscribe returns a channel receiving newly indexed events with the given
+// names (all events if none are given) and a function to cancel the
+// subscription. Events rolled back by a reorg are delivered again with
+// Removed set.
+func (i *Indexer) Subscribe(names ...string) (<-chan types.ChainEvent, func()) {
+	sub := &subscription{
+		names:  make(map[string]bool),
+		events: make(chan types.ChainEvent, subscriberBuffer),
+	}
+	for _, name := range names {
+		sub.names[name] = true
+	}
+
+	i.subscribersMux.Lock()
+	i.subscribers[sub] = true
+	i.subscribersMux.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			i.subscribersMux.Lock()
+			delete(i.subscribers, sub)
+			i.subscribersMux.Unlock()
+			close(sub.events)
+		})
+	}
+}
+
+// Events returns stored events with the given name (all events if empty) from fromBlock onwards
+func (i *Indexer) Events(name string, fromBlock uint64) ([]types.ChainEvent, error) {
+	var events []types.ChainEvent
+	err := i.store.ForEach(eventsBucket, func(_ string, value []byte) error {
+		var event types.ChainEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		if event.BlockNumber >= fromBlock && (name == "" || event.Name == name) {
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ConfirmedBlock returns the last block the indexer considers final
+func (i *Indexer) ConfirmedBlock() (uint64, error) {
+	cursor, _, err := i.cursor()
+	return cursor.Confirmed, err
+}
+
+// Run indexes events until ctx is cancelled
+func (i *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.sync(ctx); err != nil {
+			i.logger.Errorf("Event indexer sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync handles reorgs and indexes all blocks up to the current head
+func (i *Indexer) sync(ctx context.Context) error {
+	cursor, found, err := i.cursor()
+	if err != nil {
+		return err
+	}
+	if !found {
+		cursor = indexerCursor{}
+		if i.startBlock > 0 {
+			cursor.Head = i.startBlock - 1
+			cursor.Confirmed = i.startBlock - 1
+		}
+		i.logger.Infof("Event indexer starting from block %d", cursor.Head+1)
+	}
+
+	if err := i.handleReorg(ctx, &cursor); err != nil {
+		return err
+	}
+
+	head, err := i.blockchain.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	for cursor.Head < head {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		from := cursor.Head + 1
+		to := min(from+logChunkSize-1, head)
+		if err := i.indexRange(ctx, from, to, head); err != nil {
+			return err
+		}
+
+		cursor.Head = to
+		if head > i.confirmations {
+			cursor.Confirmed = max(cursor.Confirmed, min(to, head-i.confirmations))
+		}
+		if err := i.store.Put(cursorsBucket, indexerCursorKey, cursor); err != nil {
+			return err
+		}
+	}
+
+	return i.pruneBlocks(cursor.Confirmed)
+}
+
+// indexRange stores and publishes the events in blocks [from, to]
+func (i *Indexer) indexRange(ctx context.Context, from, to, head uint64) error {
+	addresses := make([]common.Address, len(i.contracts))
+	for n, contract := range i.contracts {
+		addresses[n] = contract.address
+	}
+
+	logs, err := i.blockchain.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: addresses,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch logs for blocks %d-%d: %w", from, to, err)
+	}
+
+	// Remember the hashes of unconfirmed blocks so reorgs can be detected
+	hashes := make(map[uint64]common.Hash)
+	for number := max(from, head-min(head, i.confirmations)); number <= to; number++ {
+		header, err := i.blockchain.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("failed to get header %d: %w", number, err)
+		}
+		hashes[number] = header.Hash()
+	}
+
+	var events []types.ChainEvent
+	for _, log := range logs {
+		if hash, ok := hashes[log.BlockNumber]; ok && hash != log.BlockHash {
+			return fmt.Errorf("block %d changed while indexing, retrying", log.BlockNumber)
+		}
+
+		event, ok := i.decode(log)
+		if !ok {
+			continue
+		}
+		if err := i.store.Put(eventsBucket, eventKey(event.BlockNumber, event.LogIndex), event); err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+
+	for number, hash := range hashes {
+		if err := i.store.Put(indexerBlocksBucket, blockKey(number), hash.Hex()); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		i.publish(event)
+	}
+
+	return nil
+}
+
+// handleReorg compares stored unconfirmed block hashes with the chain and
+// rolls back events from blocks that are no longer canonical
+func (i *Indexer) handleReorg(ctx context.Context, cursor *indexerCursor) error {
+	forkPoint := cursor.Head
+	for forkPoint > cursor.Confirmed {
+		var stored string
+		found, err := i.store.Get(indexerBlocksBucket, blockKey(forkPoint), &stored)
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+
+		header, err := i.blockchain.client.HeaderByNumber(ctx, new(big.Int).SetUint64(forkPoint))
+		if err != nil {
+			return fmt.Errorf("failed to get header %d: %w", forkPoint, err)
+		}
+		if header.Hash().Hex() == stored {
+			break
+		}
+		forkPoint--
+	}
+
+	if forkPoint == cursor.Head {
+		return nil
+	}
+
+	if forkPoint == cursor.Confirmed {
+		i.logger.Errorf("Chain reorg reached confirmed block %d, consider raising INDEXER_CONFIRMATIONS", forkPoint)
+	}
+	i.logger.Warnf("Chain reorg detected, rolling back blocks %d-%d", forkPoint+1, cursor.Head)
+
+	var removed []types.ChainEvent
+	err := i.store.ForEach(eventsBucket, func(_ string, value []byte) error {
+		var event types.ChainEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		if event.BlockNumber > forkPoint {
+			removed = append(removed, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+
+	// Publish removals newest first so consumers can undo in reverse order
+	for n := len(removed) - 1; n >= 0; n-- {
+		event := removed[n]
+		if err := i.store.Delete(eventsBucket, eventKey(event.BlockNumber, event.LogIndex)); err != nil {
+			return err
+		}
+		event.Removed = true
+		i.publish(event)
+	}
+
+	for number := forkPoint + 1; number <= cursor.Head; number++ {
+		if err := i.store.Delete(indexerBlocksBucket, blockKey(number)); err != nil {
+			return err
+		}
+	}
+
+	cursor.Head = forkPoint
+	return i.store.Put(cursorsBucket, indexerCursorKey, cursor)
+}
+
+// pruneBlocks drops stored hashes of blocks that are now confirmed
+func (i *Indexer) pruneBlocks(confirmed uint64) error {
+	var stale []string
+	err := i.store.ForEach(indexerBlocksBucket, func(key string, _ []byte) error {
+		if key <= blockKey(confirmed) {
+			stale = append(stale, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		if err := i.store.Delete(indexerBlocksBucket, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decode converts a raw log into a ChainEvent
+func (i *Indexer) decode(log ethtypes.Log) (types.ChainEvent, bool) {
+	if len(log.Topics) == 0 {
+		return types.ChainEvent{}, false
+	}
+
+	for _, contract := range i.contracts {
+		if contract.address != log.Address {
+			continue
+		}
+
+		event, err := contract.abi.EventByID(log.Topics[0])
+		if err != nil {
+			return types.ChainEvent{}, false
+		}
+
+		values := make(map[string]interface{})
+		if err := event.Inputs.UnpackIntoMap(values, log.Data); err != nil {
+			i.logger.Warnf("Failed to decode %s event in tx %s: %v", event.Name, log.TxHash.Hex(), err)
+			return types.ChainEvent{}, false
+		}
+
+		var indexed abi.Arguments
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed = append(indexed, input)
+			}
+		}
+		if err := abi.ParseTopicsIntoMap(values, indexed, log.Topics[1:]); err != nil {
+			i.logger.Warnf("Failed to decode %s topics in tx %s: %v", event.Name, log.TxHash.Hex(), err)
+			return types.ChainEvent{}, false
+		}
+
+		data := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			data[key] = eventValue(value)
+		}
+
+		return types.ChainEvent{
+			Contract:    contract.name,
+			Name:        event.Name,
+			BlockNumber: log.BlockNumber,
+			BlockHash:   log.BlockHash.Hex(),
+			TxHash:      log.TxHash.Hex(),
+			LogIndex:    log.Index,
+			Data:        data,
+		}, true
+	}
+
+	return types.ChainEvent{}, false
+}
+
+// publish delivers an event to all matching subscribers without blocking the indexer
+func (i *Indexer) publish(event types.ChainEvent) {
+	i.subscribersMux.RLock()
+	defer i.subscribersMux.RUnlock()
+
+	for sub := range i.subscribers {
+		if len(sub.names) > 0 && !sub.names[event.Name] {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			i.logger.Errorf("Event subscriber is not keeping up, dropped %s event in tx %s", event.Name, event.TxHash)
+		}
+	}
+}
+
+// cursor loads the indexer cursor
+func (i *Indexer) cursor() (indexerCursor, bool, error) {
+	var cursor indexerCursor
+	found, err := i.store.Get(cursorsBucket, indexerCursorKey, &cursor)
+	return cursor, found, err
+}
+
+// eventValue converts decoded ABI values into JSON-friendly forms
+func eventValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	case common.Hash:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return hexutil.Encode(v)
+	default:
+		return v
+	}
+}
+
+// eventKey orders stored events by block and log index
+func eventKey(block uint64, logIndex uint) string {
+	return fmt.Sprintf("%020d-%06d", block, logIndex)
+}
+
+// blockKey orders stored block hashes by number
+func blockKey(number uint64) string {
+	return fmt.Sprintf("%020d", number)
+}
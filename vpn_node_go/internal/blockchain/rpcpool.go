@@ -0,0 +1,492 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// rpcSampleWeight is the weight of the newest sample in the latency and
+	// error rate moving averages
+	rpcSampleWeight = 0.2
+
+	// rpcMaxErrorRate is the error rate above which an endpoint is unhealthy
+	rpcMaxErrorRate = 0.5
+)
+
+// errNoEndpoints is returned when every RPC endpoint has been excluded
+var errNoEndpoints = errors.New("no usable RPC endpoints")
+
+// rpcEndpoint is a single RPC URL and its health statistics
+type rpcEndpoint struct {
+	label  string
+	client *ethclient.Client
+
+	mu           sync.Mutex
+	head         uint64
+	lag          uint64
+	latency      time.Duration
+	errorRate    float64
+	reachable    bool
+	chainChecked bool
+	wrongChain   bool
+	lastError    string
+	lastCheck    time.Time
+}
+
+// RPCPool spreads calls over several RPC endpoints. Endpoints are ranked by
+// block height lag, latency and error rate; read-only calls fail over to the
+// next endpoint, while transactions are only sent to the best one.
+type RPCPool struct {
+	endpoints []*rpcEndpoint
+	timeout   time.Duration
+	interval  time.Duration
+	maxLag    uint64
+	logger    *logrus.Logger
+
+	mu      sync.Mutex
+	chainID *big.Int
+}
+
+// NewRPCPool dials every configured RPC endpoint and runs an initial health check
+func NewRPCPool(config *types.NodeConfig, logger *logrus.Logger) (*RPCPool, error) {
+	urls := config.RPCURLs
+	if len(urls) == 0 {
+		urls = []string{config.RPCURL}
+	}
+
+	pool := &RPCPool{
+		timeout:  config.RPCTimeout,
+		interval: config.RPCHealthInterval,
+		maxLag:   config.RPCMaxBlockLag,
+		logger:   logger,
+	}
+
+	for _, rawURL := range urls {
+		client, err := ethclient.Dial(rawURL)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to connect to RPC %s: %w", endpointLabel(rawURL), err)
+		}
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{
+			label:     endpointLabel(rawURL),
+			client:    client,
+			reachable: true,
+		})
+	}
+
+	pool.checkAll(context.Background())
+	return pool, nil
+}
+
+// Run re-checks endpoint health until ctx is cancelled
+func (p *RPCPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.checkAll(ctx)
+	}
+}
+
+// Status returns the health of every endpoint, best first
+func (p *RPCPool) Status() []types.RPCEndpointStatus {
+	endpoints := p.ranked()
+	status := make([]types.RPCEndpointStatus, 0, len(endpoints))
+
+	for _, e := range endpoints {
+		e.mu.Lock()
+		status = append(status, types.RPCEndpointStatus{
+			URL:         e.label,
+			Healthy:     p.healthy(e),
+			BlockNumber: e.head,
+			BlockLag:    e.lag,
+			LatencyMs:   e.latency.Milliseconds(),
+			ErrorRate:   e.errorRate,
+			LastError:   e.lastError,
+			LastChecked: e.lastCheck.Unix(),
+		})
+		e.mu.Unlock()
+	}
+
+	return status
+}
+
+// Close closes every endpoint connection
+func (p *RPCPool) Close() {
+	for _, e := range p.endpoints {
+		e.client.Close()
+	}
+}
+
+// checkAll measures head and latency of every endpoint and updates block lag
+func (p *RPCPool) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *rpcEndpoint) {
+			defer wg.Done()
+			p.check(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if e.reachable {
+			best = max(best, e.head)
+		}
+		e.mu.Unlock()
+	}
+
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if e.reachable && best > e.head {
+			e.lag = best - e.head
+		} else {
+			e.lag = 0
+		}
+		if e.reachable && e.lag > p.maxLag {
+			p.logger.Warnf("RPC %s is %d blocks behind", e.label, e.lag)
+		}
+		e.mu.Unlock()
+	}
+}
+
+// check probes a single endpoint and verifies it serves the same chain as the others
+func (p *RPCPool) check(ctx context.Context, e *rpcEndpoint) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	head, err := e.client.BlockNumber(ctx)
+	latency := time.Since(start)
+
+	e.mu.Lock()
+	e.lastCheck = time.Now()
+	if err != nil {
+		e.reachable = false
+		e.mu.Unlock()
+		e.record(err)
+		p.logger.Warnf("RPC %s health check failed: %v", e.label, err)
+		return
+	}
+
+	e.reachable = true
+	e.head = head
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(rpcSampleWeight*float64(latency) + (1-rpcSampleWeight)*float64(e.latency))
+	}
+	checked := e.chainChecked
+	e.mu.Unlock()
+	e.record(nil)
+
+	if !checked {
+		p.checkChainID(ctx, e)
+	}
+}
+
+// checkChainID excludes an endpoint whose chain ID differs from the first one seen
+func (p *RPCPool) checkChainID(ctx context.Context, e *rpcEndpoint) {
+	chainID, err := e.client.ChainID(ctx)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	if p.chainID == nil {
+		p.chainID = chainID
+	}
+	wrongChain := p.chainID.Cmp(chainID) != 0
+	expected := p.chainID
+	p.mu.Unlock()
+
+	e.mu.Lock()
+	e.chainChecked = true
+	e.wrongChain = wrongChain
+	if wrongChain {
+		e.lastError = fmt.Sprintf("chain ID %s does not match %s", chainID, expected)
+	}
+	e.mu.Unlock()
+
+	if wrongChain {
+		p.logger.Errorf("RPC %s serves chain %s instead of %s, excluding it", e.label, chainID, expected)
+	}
+}
+
+// healthy reports whether an endpoint is usable; e.mu must be held
+func (p *RPCPool) healthy(e *rpcEndpoint) bool {
+	return e.reachable && !e.wrongChain && e.lag <= p.maxLag && e.errorRate <= rpcMaxErrorRate
+}
+
+// ranked returns the usable endpoints, healthy ones first and then by score
+func (p *RPCPool) ranked() []*rpcEndpoint {
+	type candidate struct {
+		endpoint *rpcEndpoint
+		healthy  bool
+		score    float64
+	}
+
+	candidates := make([]candidate, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if !e.wrongChain {
+			candidates = append(candidates, candidate{
+				endpoint: e,
+				healthy:  p.healthy(e),
+				// Lower is better: a block of lag costs as much as 100ms of latency
+				score: float64(e.latency.Milliseconds())*(1+4*e.errorRate) + 100*float64(e.lag),
+			})
+		}
+		e.mu.Unlock()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].healthy != candidates[j].healthy {
+			return candidates[i].healthy
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	endpoints := make([]*rpcEndpoint, len(candidates))
+	for i, c := range candidates {
+		endpoints[i] = c.endpoint
+	}
+	return endpoints
+}
+
+// record updates the error rate of an endpoint with the outcome of a request
+func (e *rpcEndpoint) record(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sample := 0.0
+	if err != nil {
+		sample = 1
+		e.lastError = err.Error()
+	}
+	e.errorRate = rpcSampleWeight*sample + (1-rpcSampleWeight)*e.errorRate
+}
+
+// read runs a read-only call on the best endpoint, retrying on the next one
+// when the endpoint itself failed
+func read[T any](p *RPCPool, ctx context.Context, call func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	var (
+		result T
+		err    = errNoEndpoints
+	)
+
+	for _, e := range p.ranked() {
+		callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		result, err = call(callCtx, e.client)
+		cancel()
+
+		if err == nil || !isEndpointError(ctx, err) {
+			e.record(nil)
+			return result, err
+		}
+
+		e.record(err)
+		p.logger.Warnf("RPC %s failed, trying next endpoint: %v", e.label, err)
+	}
+
+	return result, err
+}
+
+// write runs a state-changing call on the best endpoint only
+func write[T any](p *RPCPool, ctx context.Context, call func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	var result T
+
+	endpoints := p.ranked()
+	if len(endpoints) == 0 {
+		return result, errNoEndpoints
+	}
+
+	e := endpoints[0]
+	result, err := call(ctx, e.client)
+	if err != nil && isEndpointError(ctx, err) {
+		e.record(err)
+	} else {
+		e.record(nil)
+	}
+	return result, err
+}
+
+// isEndpointError reports whether err is a failure of the endpoint rather than
+// an answer from the chain, such as a revert or a missing receipt
+func isEndpointError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) || strings.Contains(err.Error(), "execution reverted") {
+		return false
+	}
+
+	return true
+}
+
+// endpointLabel strips the path and credentials from an RPC URL, which often
+// contain API keys
+func endpointLabel(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "invalid-url"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// Consistent runs call against a single endpoint, failing over as a whole,
+// so that the results of several requests describe the same chain view
+func (p *RPCPool) Consistent(ctx context.Context, call func(context.Context, *ethclient.Client) error) error {
+	_, err := read(p, ctx, func(ctx context.Context, c *ethclient.Client) (struct{}, error) {
+		return struct{}{}, call(ctx, c)
+	})
+	return err
+}
+
+// BlockNumber returns the most recent block number
+func (p *RPCPool) BlockNumber(ctx context.Context) (uint64, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.BlockNumber(ctx)
+	})
+}
+
+// ChainID returns the chain ID of the endpoints
+func (p *RPCPool) ChainID(ctx context.Context) (*big.Int, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.ChainID(ctx)
+	})
+}
+
+// HeaderByNumber returns a block header; nil returns the latest header
+func (p *RPCPool) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (*ethtypes.Header, error) {
+		return c.HeaderByNumber(ctx, number)
+	})
+}
+
+// TransactionReceipt returns the receipt of a mined transaction
+func (p *RPCPool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (*ethtypes.Receipt, error) {
+		return c.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// TransactionByHash returns a transaction and whether it is still pending
+func (p *RPCPool) TransactionByHash(ctx context.Context, txHash common.Hash) (*ethtypes.Transaction, bool, error) {
+	type result struct {
+		tx      *ethtypes.Transaction
+		pending bool
+	}
+
+	r, err := read(p, ctx, func(ctx context.Context, c *ethclient.Client) (result, error) {
+		tx, pending, err := c.TransactionByHash(ctx, txHash)
+		return result{tx, pending}, err
+	})
+	return r.tx, r.pending, err
+}
+
+// NonceAt returns the account nonce at the given block
+func (p *RPCPool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+// PendingNonceAt returns the account nonce including pending transactions
+func (p *RPCPool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.PendingNonceAt(ctx, account)
+	})
+}
+
+// SuggestGasPrice returns the suggested legacy gas price
+func (p *RPCPool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap returns the suggested EIP-1559 priority fee
+func (p *RPCPool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.SuggestGasTipCap(ctx)
+	})
+}
+
+// EstimateGas estimates the gas needed to execute msg
+func (p *RPCPool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.EstimateGas(ctx, msg)
+	})
+}
+
+// CallContract executes a contract call
+func (p *RPCPool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) ([]byte, error) {
+		return c.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+// CodeAt returns the contract code of an account
+func (p *RPCPool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) ([]byte, error) {
+		return c.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+// PendingCodeAt returns the contract code of an account in the pending state
+func (p *RPCPool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) ([]byte, error) {
+		return c.PendingCodeAt(ctx, account)
+	})
+}
+
+// FilterLogs returns the logs matching query
+func (p *RPCPool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	return read(p, ctx, func(ctx context.Context, c *ethclient.Client) ([]ethtypes.Log, error) {
+		return c.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs subscribes to logs on the best endpoint
+func (p *RPCPool) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- ethtypes.Log) (ethereum.Subscription, error) {
+	return write(p, ctx, func(ctx context.Context, c *ethclient.Client) (ethereum.Subscription, error) {
+		return c.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// SendTransaction broadcasts a signed transaction to the best endpoint
+func (p *RPCPool) SendTransaction(ctx context.Context, tx *ethtypes.Transaction) error {
+	_, err := write(p, ctx, func(ctx context.Context, c *ethclient.Client) (struct{}, error) {
+		return struct{}{}, c.SendTransaction(ctx, tx)
+	})
+	return err
+}
@@ -0,0 +1,346 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// remoteSignTimeout bounds a single signing request to a remote signer
+const remoteSignTimeout = 30 * time.Second
+
+// Signer signs transactions and messages for the node wallet
+type Signer interface {
+	// Address returns the account the signer signs for
+	Address() common.Address
+
+	// SignTx returns a copy of tx signed for chainID
+	SignTx(ctx context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error)
+
+	// SignMessage returns the 65-byte EIP-191 (personal_sign) signature of
+	// message, with V as 27 or 28
+	SignMessage(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// KeySigner signs with an in-memory private key
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates a signer for key
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// Address returns the address of the private key
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs tx with the private key
+func (s *KeySigner) SignTx(_ context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	return ethtypes.SignTx(tx, ethtypes.LatestSignerForChainID(chainID), s.key)
+}
+
+// SignMessage signs message with the EIP-191 prefix
+func (s *KeySigner) SignMessage(_ context.Context, message []byte) ([]byte, error) {
+	signature, err := crypto.Sign(accounts.TextHash(message), s.key)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// LoadKeystore decrypts a geth-style JSON keystore file
+func LoadKeystore(path, passphrase string) (*KeySigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// RemoteSigner delegates signing to an external JSON-RPC signer such as clef
+// (account_signTransaction) or web3signer (eth_signTransaction). The private
+// key never enters the node process.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+	method  string
+}
+
+// remoteTxArgs is the transaction object sent to the remote signer
+type remoteTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// NewRemoteSigner connects to a remote signer for address using method
+func NewRemoteSigner(url string, address common.Address, method string) (*RemoteSigner, error) {
+	switch method {
+	case "eth_signTransaction", "account_signTransaction":
+	default:
+		return nil, fmt.Errorf("unsupported remote signer method: %s", method)
+	}
+
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+
+	return &RemoteSigner{
+		client:  client,
+		address: address,
+		method:  method,
+	}, nil
+}
+
+// Address returns the account the remote signer signs for
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx asks the remote signer to sign tx and checks the result matches the request
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	args := remoteTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == ethtypes.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remoteSignTimeout)
+	defer cancel()
+
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, s.method, args); err != nil {
+		return nil, fmt.Errorf("remote signer rejected transaction: %w", err)
+	}
+
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(ethtypes.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed transaction: %w", err)
+	}
+
+	if err := checkSignedTx(tx, signed, s.address, chainID); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// SignMessage asks the remote signer for an EIP-191 signature of message,
+// using account_signData for clef and eth_sign otherwise
+func (s *RemoteSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteSignTimeout)
+	defer cancel()
+
+	var (
+		signature hexutil.Bytes
+		err       error
+	)
+	if s.method == "account_signTransaction" {
+		err = s.client.CallContext(ctx, &signature, "account_signData", accounts.MimetypeTextPlain, s.address, hexutil.Bytes(message))
+	} else {
+		err = s.client.CallContext(ctx, &signature, "eth_sign", s.address, hexutil.Bytes(message))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remote signer rejected message: %w", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("unexpected signature length %d from remote signer", len(signature))
+	}
+
+	// Some signers return V as 0/1
+	if signature[crypto.RecoveryIDOffset] < 27 {
+		signature[crypto.RecoveryIDOffset] += 27
+	}
+
+	if err := checkSignature(message, signature, s.address); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// Close closes the connection to the remote signer
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// decodeSignResult extracts the raw transaction from a signer response, which
+// is either a hex string or an object with a "raw" field
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+
+	var object struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &object); err != nil || len(object.Raw) == 0 {
+		return nil, fmt.Errorf("unexpected remote signer response: %s", string(result))
+	}
+	return object.Raw, nil
+}
+
+// checkSignedTx verifies a remotely signed transaction is the one that was requested
+func checkSignedTx(want, got *ethtypes.Transaction, from common.Address, chainID *big.Int) error {
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(chainID), got)
+	if err != nil {
+		return fmt.Errorf("invalid signature from remote signer: %w", err)
+	}
+	if sender != from {
+		return fmt.Errorf("remote signer signed for %s, expected %s", sender.Hex(), from.Hex())
+	}
+
+	// Fees are compared as well, so the signer cannot raise what the node pays
+	sameTo := (want.To() == nil) == (got.To() == nil) && (want.To() == nil || *want.To() == *got.To())
+	switch {
+	case got.Type() != want.Type(),
+		got.Nonce() != want.Nonce(),
+		!sameTo,
+		got.Gas() != want.Gas(),
+		got.GasTipCap().Cmp(want.GasTipCap()) != 0,
+		got.GasFeeCap().Cmp(want.GasFeeCap()) != 0,
+		got.Value().Cmp(want.Value()) != 0,
+		!bytes.Equal(got.Data(), want.Data()):
+		return errors.New("remote signer returned a different transaction than requested")
+	}
+	return nil
+}
+
+// checkSignature verifies an EIP-191 signature of message was made by from
+func checkSignature(message, signature []byte, from common.Address) error {
+	sig := append([]byte(nil), signature...)
+	sig[crypto.RecoveryIDOffset] -= 27
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash(message), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature from remote signer: %w", err)
+	}
+	if signer := crypto.PubkeyToAddress(*publicKey); signer != from {
+		return fmt.Errorf("remote signer signed for %s, expected %s", signer.Hex(), from.Hex())
+	}
+	return nil
+}
+
+// newSigner builds the signer selected by the node configuration. A remote
+// signer takes precedence over a keystore, which takes precedence over a raw
+// PRIVATE_KEY.
+func newSigner(config *types.NodeConfig, logger *logrus.Logger) (Signer, error) {
+	switch {
+	case config.RemoteSignerURL != "":
+		if !common.IsHexAddress(config.RemoteSignerAddress) {
+			return nil, fmt.Errorf("invalid REMOTE_SIGNER_ADDRESS: %q", config.RemoteSignerAddress)
+		}
+		logger.Infof("Using remote signer at %s", config.RemoteSignerURL)
+		return NewRemoteSigner(config.RemoteSignerURL, common.HexToAddress(config.RemoteSignerAddress), config.RemoteSignerMethod)
+
+	case config.KeystorePath != "":
+		passphrase, err := readPassphrase(config.KeystorePasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("Using keystore %s", config.KeystorePath)
+		return LoadKeystore(config.KeystorePath, passphrase)
+
+	case config.PrivateKey != "":
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		logger.Warn("Using raw PRIVATE_KEY; prefer KEYSTORE_PATH or REMOTE_SIGNER_URL")
+		return NewKeySigner(key), nil
+	}
+
+	return nil, errors.New("no signer configured: set REMOTE_SIGNER_URL, KEYSTORE_PATH or PRIVATE_KEY")
+}
+
+// readPassphrase reads the keystore passphrase from file, or prompts for it
+// on the terminal when no file is configured
+func readPassphrase(file string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read keystore password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("KEYSTORE_PASSWORD_FILE is required when not running in a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, "Keystore passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// signerFn adapts signer to the callback used by bind.TransactOpts
+func signerFn(signer Signer, chainID *big.Int) bind.SignerFn {
+	return func(address common.Address, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+		if address != signer.Address() {
+			return nil, bind.ErrNotAuthorized
+		}
+		return signer.SignTx(context.Background(), tx, chainID)
+	}
+}
@@ -0,0 +1,233 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var testChainID = big.NewInt(31337)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func newTestTx() *ethtypes.Transaction {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	return ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(2_000_000_000),
+		GasFeeCap: big.NewInt(30_000_000_000),
+		Gas:       100_000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+		Data:      []byte{0xde, 0xad, 0xbe, 0xef},
+	})
+}
+
+func TestKeySigner(t *testing.T) {
+	key := newTestKey(t)
+	signer := NewKeySigner(key)
+	if signer.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("address = %s, want the key's address", signer.Address().Hex())
+	}
+
+	tx := newTestTx()
+	signed, err := signer.SignTx(context.Background(), tx, testChainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	if err := checkSignedTx(tx, signed, signer.Address(), testChainID); err != nil {
+		t.Fatalf("signed transaction does not match: %v", err)
+	}
+
+	message := []byte("hello")
+	signature, err := signer.SignMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("SignMessage: %v", err)
+	}
+	if v := signature[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+		t.Fatalf("V = %d, want 27 or 28", v)
+	}
+	if err := checkSignature(message, signature, signer.Address()); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	if err := checkSignature([]byte("other"), signature, signer.Address()); err == nil {
+		t.Fatal("signature verified for a different message")
+	}
+}
+
+func TestLoadKeystore(t *testing.T) {
+	key := newTestKey(t)
+	encrypted, err := keystore.EncryptKey(&keystore.Key{
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := os.WriteFile(path, encrypted, 0600); err != nil {
+		t.Fatalf("failed to write keystore: %v", err)
+	}
+
+	signer, err := LoadKeystore(path, "correct horse")
+	if err != nil {
+		t.Fatalf("LoadKeystore: %v", err)
+	}
+	if signer.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("address = %s, want the key's address", signer.Address().Hex())
+	}
+
+	if _, err := LoadKeystore(path, "wrong passphrase"); err == nil {
+		t.Fatal("LoadKeystore accepted a wrong passphrase")
+	}
+	if _, err := LoadKeystore(filepath.Join(t.TempDir(), "missing.json"), "correct horse"); err == nil {
+		t.Fatal("LoadKeystore accepted a missing file")
+	}
+}
+
+// clefStub answers clef's account_* methods, signing with key after letting
+// tamper change the requested transaction
+type clefStub struct {
+	key    *ecdsa.PrivateKey
+	tamper func(*ethtypes.DynamicFeeTx)
+}
+
+func (c *clefStub) SignTransaction(args remoteTxArgs) (map[string]hexutil.Bytes, error) {
+	inner := &ethtypes.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	}
+	if c.tamper != nil {
+		c.tamper(inner)
+	}
+
+	signed, err := ethtypes.SignNewTx(c.key, ethtypes.LatestSignerForChainID(inner.ChainID), inner)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]hexutil.Bytes{"raw": raw}, nil
+}
+
+func (c *clefStub) SignData(mimetype string, address common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	signature, err := crypto.Sign(accounts.TextHash(data), c.key)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+func newClefStub(t *testing.T, stub *clefStub, address common.Address) *RemoteSigner {
+	t.Helper()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", stub); err != nil {
+		t.Fatalf("failed to register clef stub: %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+
+	signer, err := NewRemoteSigner(httpServer.URL, address, "account_signTransaction")
+	if err != nil {
+		t.Fatalf("NewRemoteSigner: %v", err)
+	}
+	t.Cleanup(signer.Close)
+	return signer
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key := newTestKey(t)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := newClefStub(t, &clefStub{key: key}, address)
+	tx := newTestTx()
+	signed, err := signer.SignTx(context.Background(), tx, testChainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(testChainID), signed)
+	if err != nil || sender != address {
+		t.Fatalf("sender = %s (%v), want %s", sender.Hex(), err, address.Hex())
+	}
+
+	message := []byte("hello")
+	signature, err := signer.SignMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("SignMessage: %v", err)
+	}
+	if err := checkSignature(message, signature, address); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+}
+
+func TestRemoteSignerRejectsAlteredTransaction(t *testing.T) {
+	key := newTestKey(t)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	tests := []struct {
+		name   string
+		tamper func(*ethtypes.DynamicFeeTx)
+	}{
+		{"nonce", func(tx *ethtypes.DynamicFeeTx) { tx.Nonce++ }},
+		{"recipient", func(tx *ethtypes.DynamicFeeTx) { tx.To = &common.Address{0x01} }},
+		{"gas", func(tx *ethtypes.DynamicFeeTx) { tx.Gas *= 2 }},
+		{"tip cap", func(tx *ethtypes.DynamicFeeTx) { tx.GasTipCap = new(big.Int).Add(tx.GasTipCap, big.NewInt(1)) }},
+		{"fee cap", func(tx *ethtypes.DynamicFeeTx) { tx.GasFeeCap = new(big.Int).Add(tx.GasFeeCap, big.NewInt(1)) }},
+		{"value", func(tx *ethtypes.DynamicFeeTx) { tx.Value = big.NewInt(0) }},
+		{"data", func(tx *ethtypes.DynamicFeeTx) { tx.Data = nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := newClefStub(t, &clefStub{key: key, tamper: tt.tamper}, address)
+			if _, err := signer.SignTx(context.Background(), newTestTx(), testChainID); err == nil {
+				t.Fatal("SignTx accepted a transaction with a different " + tt.name)
+			}
+		})
+	}
+}
+
+func TestRemoteSignerRejectsWrongAccount(t *testing.T) {
+	signer := newClefStub(t, &clefStub{key: newTestKey(t)}, crypto.PubkeyToAddress(newTestKey(t).PublicKey))
+
+	if _, err := signer.SignTx(context.Background(), newTestTx(), testChainID); err == nil {
+		t.Fatal("SignTx accepted a transaction signed by another account")
+	}
+	if _, err := signer.SignMessage(context.Background(), []byte("hello")); err == nil {
+		t.Fatal("SignMessage accepted a signature from another account")
+	}
+}
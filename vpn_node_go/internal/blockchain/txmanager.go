@@ -0,0 +1,684 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// txTimeout bounds how long a transaction may take from submission to confirmation
+	txTimeout = 10 * time.Minute
+
+	// txPollInterval is how often pending transactions are checked
+	txPollInterval = 3 * time.Second
+
+	// txRetention is how long finished transactions are kept in the store
+	txRetention = 24 * time.Hour
+
+	txBucket = "transactions"
+)
+
+// Transaction record statuses
+const (
+	txStatusPending   = "pending"
+	txStatusConfirmed = "confirmed"
+	txStatusReverted  = "reverted"
+	txStatusDropped   = "dropped"
+)
+
+// ErrTxDropped is returned when a transaction's nonce was consumed by another transaction
+var ErrTxDropped = errors.New("transaction dropped")
+
+// RevertError is returned when a transaction is rejected by the EVM
+type RevertError struct {
+	TxHash string
+	Reason string
+}
+
+func (e *RevertError) Error() string {
+	if e.TxHash == "" {
+		return fmt.Sprintf("transaction reverted: %s", e.Reason)
+	}
+	return fmt.Sprintf("transaction %s reverted: %s", e.TxHash, e.Reason)
+}
+
+// txRecord is the persisted state of a transaction sent by the TxManager
+type txRecord struct {
+	Key          string    `json:"key"`
+	Nonce        uint64    `json:"nonce"`
+	Status       string    `json:"status"`
+	Hashes       []string  `json:"hashes"` // every broadcast version, newest last
+	RawTx        string    `json:"rawTx"`  // latest signed version
+	MinedHash    string    `json:"minedHash,omitempty"`
+	Replacements int       `json:"replacements"`
+	NonceUsedAt  uint64    `json:"nonceUsedAt,omitempty"` // head when the nonce was first seen used without a receipt
+	SubmittedAt  time.Time `json:"submittedAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// TxManager owns the node wallet nonce and drives transactions from
+// submission to confirmation. Sends are serialised so nonces are assigned in
+// call order, and every transaction is persisted before it is broadcast so a
+// restart resumes tracking instead of sending it again.
+type TxManager struct {
+	client        *RPCPool
+	store         *store.Store
+	logger        *logrus.Logger
+	from          common.Address
+	signer        bind.SignerFn
+	confirmations uint64
+	stuckTimeout  time.Duration
+	feeBump       int64
+	maxFeeCap     *big.Int
+
+	mu          sync.Mutex
+	nonce       uint64
+	nonceSynced bool
+}
+
+// NewTxManager creates a transaction manager for the wallet behind signer
+func NewTxManager(config *types.NodeConfig, client *RPCPool, db *store.Store, from common.Address, signer bind.SignerFn, logger *logrus.Logger) (*TxManager, error) {
+	if config.TxFeeBumpPercent < 10 {
+		return nil, fmt.Errorf("TX_FEE_BUMP_PERCENT must be at least 10, got %d", config.TxFeeBumpPercent)
+	}
+
+	var maxFeeCap *big.Int
+	if config.TxMaxFeeCap != "" {
+		value, ok := new(big.Int).SetString(config.TxMaxFeeCap, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid TX_MAX_FEE_CAP: %s", config.TxMaxFeeCap)
+		}
+		maxFeeCap = value
+	}
+
+	confirmations := config.TxConfirmations
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	return &TxManager{
+		client:        client,
+		store:         db,
+		logger:        logger,
+		from:          from,
+		signer:        signer,
+		confirmations: confirmations,
+		stuckTimeout:  config.TxStuckTimeout,
+		feeBump:       int64(config.TxFeeBumpPercent),
+		maxFeeCap:     maxFeeCap,
+	}, nil
+}
+
+// Transact signs and broadcasts the transaction produced by build. The build
+// function receives options with the nonce and fees already set and must not
+// send the transaction itself (NoSend is set).
+//
+// If key is non-empty and a transaction with the same key is still pending,
+// that transaction is returned instead of sending a new one.
+func (t *TxManager) Transact(ctx context.Context, key string, build func(opts *bind.TransactOpts) (*ethtypes.Transaction, error)) (*ethtypes.Transaction, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key != "" {
+		existing, err := t.findPending(key)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			t.logger.Infof("Transaction %q already pending as %s, not sending again", key, existing.Hash().Hex())
+			return existing, nil
+		}
+	}
+
+	if err := t.syncNonce(ctx); err != nil {
+		return nil, err
+	}
+
+	opts := &bind.TransactOpts{
+		From:    t.from,
+		Signer:  t.signer,
+		Nonce:   new(big.Int).SetUint64(t.nonce),
+		Context: ctx,
+		NoSend:  true,
+	}
+	if err := t.setFees(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	tx, err := build(opts)
+	if err != nil {
+		return nil, wrapTxError(err)
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	now := time.Now()
+	record := &txRecord{
+		Key:         key,
+		Nonce:       tx.Nonce(),
+		Status:      txStatusPending,
+		Hashes:      []string{tx.Hash().Hex()},
+		RawTx:       hexutil.Encode(raw),
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	if err := t.save(record); err != nil {
+		return nil, err
+	}
+
+	if err := t.client.SendTransaction(ctx, tx); err != nil && !isKnownTxError(err) {
+		if delErr := t.store.Delete(txBucket, recordKey(record.Nonce)); delErr != nil {
+			t.logger.Errorf("Failed to discard unsent transaction %s: %v", tx.Hash().Hex(), delErr)
+		}
+		if strings.Contains(err.Error(), "nonce too low") {
+			t.nonceSynced = false
+		}
+		return nil, fmt.Errorf("failed to send transaction: %w", wrapTxError(err))
+	}
+
+	t.nonce++
+	t.logger.Infof("Sent transaction %s (nonce %d)", tx.Hash().Hex(), tx.Nonce())
+	return tx, nil
+}
+
+// Wait blocks until tx (or a fee-bumped replacement of it) has the configured
+// number of confirmations. Reverted transactions return a RevertError.
+func (t *TxManager) Wait(ctx context.Context, tx *ethtypes.Transaction) (*ethtypes.Receipt, error) {
+	t.logger.Infof("Waiting for transaction %s to be confirmed...", tx.Hash().Hex())
+
+	ticker := time.NewTicker(txPollInterval)
+	defer ticker.Stop()
+
+	for {
+		record, err := t.process(ctx, tx.Nonce())
+		if err != nil {
+			t.logger.Warnf("Failed to check transaction %s: %v", tx.Hash().Hex(), err)
+		}
+
+		if record == nil && err == nil {
+			// Not tracked by the manager, fall back to the transaction's own receipt
+			receipt, err := t.client.TransactionReceipt(ctx, tx.Hash())
+			if err == nil {
+				if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+					return receipt, &RevertError{
+						TxHash: tx.Hash().Hex(),
+						Reason: t.replayRevertReason(ctx, tx, receipt.BlockNumber),
+					}
+				}
+				return receipt, nil
+			}
+		}
+
+		if record != nil {
+			switch record.Status {
+			case txStatusConfirmed:
+				return t.client.TransactionReceipt(ctx, common.HexToHash(record.MinedHash))
+			case txStatusReverted:
+				receipt, err := t.client.TransactionReceipt(ctx, common.HexToHash(record.MinedHash))
+				if err != nil {
+					return nil, fmt.Errorf("failed to get receipt: %w", err)
+				}
+				return receipt, &RevertError{
+					TxHash: record.MinedHash,
+					Reason: t.replayRevertReason(ctx, tx, receipt.BlockNumber),
+				}
+			case txStatusDropped:
+				return nil, fmt.Errorf("%w: nonce %d was used by another transaction", ErrTxDropped, record.Nonce)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed waiting for transaction %s: %w", tx.Hash().Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run tracks all persisted transactions, including ones left pending by a
+// previous run, rebroadcasting or fee-bumping them until they are mined.
+func (t *TxManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(10 * txPollInterval)
+	defer ticker.Stop()
+
+	for {
+		records, err := t.records()
+		if err != nil {
+			t.logger.Errorf("Failed to load transactions: %v", err)
+		}
+
+		for _, record := range records {
+			if record.Status != txStatusPending {
+				if time.Since(record.UpdatedAt) > txRetention {
+					if err := t.store.Delete(txBucket, recordKey(record.Nonce)); err != nil {
+						t.logger.Errorf("Failed to prune transaction record: %v", err)
+					}
+				}
+				continue
+			}
+
+			if _, err := t.process(ctx, record.Nonce); err != nil {
+				t.logger.Warnf("Failed to check transaction with nonce %d: %v", record.Nonce, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// process advances the pending transaction with the given nonce and returns its latest record
+func (t *TxManager) process(ctx context.Context, nonce uint64) (*txRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var record txRecord
+	found, err := t.store.Get(txBucket, recordKey(nonce), &record)
+	if err != nil || !found {
+		return nil, err
+	}
+	if record.Status != txStatusPending {
+		return &record, nil
+	}
+
+	// Any of the broadcast versions may be the one that was mined
+	receipt, head, err := t.findReceipt(ctx, record.Hashes, t.client.TransactionReceipt, t.client.BlockNumber)
+	if err != nil {
+		return &record, err
+	}
+
+	if receipt == nil {
+		// The nonce and the receipts are read from one endpoint, so a
+		// transaction mined between the calls or an endpoint lagging behind
+		// the others is not mistaken for a drop
+		var minedNonce uint64
+		err := t.client.Consistent(ctx, func(ctx context.Context, c *ethclient.Client) error {
+			var err error
+			if minedNonce, err = c.NonceAt(ctx, t.from, nil); err != nil {
+				return fmt.Errorf("failed to get nonce: %w", err)
+			}
+			if minedNonce <= record.Nonce {
+				return nil
+			}
+			receipt, head, err = t.findReceipt(ctx, record.Hashes, c.TransactionReceipt, c.BlockNumber)
+			if err != nil || receipt != nil {
+				return err
+			}
+			if head, err = c.BlockNumber(ctx); err != nil {
+				return fmt.Errorf("failed to get block number: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return &record, err
+		}
+
+		if receipt == nil && minedNonce > record.Nonce {
+			// Only give up once the nonce has been used by something else
+			// for as long as a receipt takes to be confirmed
+			if record.NonceUsedAt == 0 {
+				record.NonceUsedAt = head
+				return &record, t.save(&record)
+			}
+			if head+1 < record.NonceUsedAt+t.confirmations {
+				return &record, nil
+			}
+			t.logger.Warnf("Transaction with nonce %d was replaced by an unknown transaction", record.Nonce)
+			record.Status = txStatusDropped
+			return &record, t.save(&record)
+		}
+	}
+
+	if receipt != nil {
+		if head+1 < receipt.BlockNumber.Uint64()+t.confirmations {
+			return &record, nil
+		}
+
+		record.MinedHash = receipt.TxHash.Hex()
+		record.Status = txStatusConfirmed
+		if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+			record.Status = txStatusReverted
+		}
+		return &record, t.save(&record)
+	}
+
+	if record.NonceUsedAt != 0 {
+		// The nonce is unused again, e.g. after a reorg
+		record.NonceUsedAt = 0
+		if err := t.save(&record); err != nil {
+			return &record, err
+		}
+	}
+
+	if t.stuckTimeout > 0 && time.Since(record.SubmittedAt) > t.stuckTimeout {
+		return &record, t.replace(ctx, &record)
+	}
+
+	// Rebroadcast if the node no longer knows about the transaction, e.g.
+	// after a restart or mempool eviction
+	latest := common.HexToHash(record.Hashes[len(record.Hashes)-1])
+	if _, _, err := t.client.TransactionByHash(ctx, latest); errors.Is(err, ethereum.NotFound) {
+		tx, err := decodeTx(record.RawTx)
+		if err != nil {
+			return &record, err
+		}
+		t.logger.Infof("Rebroadcasting transaction %s", latest.Hex())
+		if err := t.client.SendTransaction(ctx, tx); err != nil && !isKnownTxError(err) {
+			return &record, fmt.Errorf("failed to rebroadcast transaction: %w", err)
+		}
+	}
+
+	return &record, nil
+}
+
+// findReceipt returns the receipt of whichever of hashes was mined, newest
+// first, together with the head block; a nil receipt means none was mined
+func (t *TxManager) findReceipt(ctx context.Context, hashes []string, receiptOf func(context.Context, common.Hash) (*ethtypes.Receipt, error), blockNumber func(context.Context) (uint64, error)) (*ethtypes.Receipt, uint64, error) {
+	for i := len(hashes) - 1; i >= 0; i-- {
+		receipt, err := receiptOf(ctx, common.HexToHash(hashes[i]))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get receipt: %w", err)
+		}
+
+		head, err := blockNumber(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get block number: %w", err)
+		}
+		return receipt, head, nil
+	}
+	return nil, 0, nil
+}
+
+// replace re-signs a stuck transaction with the same nonce and bumped fees
+func (t *TxManager) replace(ctx context.Context, record *txRecord) error {
+	old, err := decodeTx(record.RawTx)
+	if err != nil {
+		return err
+	}
+
+	opts := &bind.TransactOpts{}
+	if err := t.setFees(ctx, opts); err != nil {
+		return err
+	}
+
+	var replacement *ethtypes.Transaction
+	if old.Type() == ethtypes.LegacyTxType {
+		gasPrice := maxBig(opts.GasPrice, t.bump(old.GasPrice()))
+		if t.maxFeeCap != nil && gasPrice.Cmp(t.maxFeeCap) > 0 {
+			t.logger.Warnf("Not bumping transaction %s: gas price would exceed TX_MAX_FEE_CAP", old.Hash().Hex())
+			return nil
+		}
+		replacement = ethtypes.NewTx(&ethtypes.LegacyTx{
+			Nonce:    old.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      old.Gas(),
+			To:       old.To(),
+			Value:    old.Value(),
+			Data:     old.Data(),
+		})
+	} else {
+		tipCap := maxBig(opts.GasTipCap, t.bump(old.GasTipCap()))
+		feeCap := maxBig(opts.GasFeeCap, t.bump(old.GasFeeCap()))
+		if t.maxFeeCap != nil && feeCap.Cmp(t.maxFeeCap) > 0 {
+			t.logger.Warnf("Not bumping transaction %s: fee cap would exceed TX_MAX_FEE_CAP", old.Hash().Hex())
+			return nil
+		}
+		if tipCap.Cmp(feeCap) > 0 {
+			tipCap = feeCap
+		}
+		replacement = ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+			Nonce:      old.Nonce(),
+			GasTipCap:  tipCap,
+			GasFeeCap:  feeCap,
+			Gas:        old.Gas(),
+			To:         old.To(),
+			Value:      old.Value(),
+			Data:       old.Data(),
+			AccessList: old.AccessList(),
+		})
+	}
+
+	signed, err := t.signer(t.from, replacement)
+	if err != nil {
+		return fmt.Errorf("failed to sign replacement transaction: %w", err)
+	}
+
+	if err := t.client.SendTransaction(ctx, signed); err != nil && !isKnownTxError(err) {
+		return fmt.Errorf("failed to send replacement transaction: %w", err)
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	t.logger.Infof("Replaced stuck transaction %s with %s", old.Hash().Hex(), signed.Hash().Hex())
+
+	record.Hashes = append(record.Hashes, signed.Hash().Hex())
+	record.RawTx = hexutil.Encode(raw)
+	record.Replacements++
+	record.SubmittedAt = time.Now()
+	return t.save(record)
+}
+
+// setFees fills in EIP-1559 fee caps, or a legacy gas price on chains without a base fee
+func (t *TxManager) setFees(ctx context.Context, opts *bind.TransactOpts) error {
+	head, err := t.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get latest header: %w", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := t.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to suggest gas price: %w", err)
+		}
+		opts.GasPrice = gasPrice
+		return nil
+	}
+
+	tipCap, err := t.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to suggest gas tip cap: %w", err)
+	}
+
+	// Leave room for the base fee to double before the transaction becomes unmineable
+	feeCap := new(big.Int).Add(tipCap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	if t.maxFeeCap != nil && feeCap.Cmp(t.maxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(t.maxFeeCap)
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+
+	opts.GasTipCap = tipCap
+	opts.GasFeeCap = feeCap
+	return nil
+}
+
+// syncNonce loads the next nonce from the chain and any transactions still pending in the store
+func (t *TxManager) syncNonce(ctx context.Context) error {
+	if t.nonceSynced {
+		return nil
+	}
+
+	nonce, err := t.client.PendingNonceAt(ctx, t.from)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	records, err := t.records()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Status == txStatusPending && record.Nonce >= nonce {
+			nonce = record.Nonce + 1
+		}
+	}
+
+	t.nonce = nonce
+	t.nonceSynced = true
+	return nil
+}
+
+// findPending returns the pending transaction sent with key, if any
+func (t *TxManager) findPending(key string) (*ethtypes.Transaction, error) {
+	records, err := t.records()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Key == key && record.Status == txStatusPending {
+			return decodeTx(record.RawTx)
+		}
+	}
+
+	return nil, nil
+}
+
+// records loads every persisted transaction record in nonce order
+func (t *TxManager) records() ([]txRecord, error) {
+	var records []txRecord
+	err := t.store.ForEach(txBucket, func(_ string, value []byte) error {
+		var record txRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	return records, nil
+}
+
+// save persists a transaction record
+func (t *TxManager) save(record *txRecord) error {
+	record.UpdatedAt = time.Now()
+	if err := t.store.Put(txBucket, recordKey(record.Nonce), record); err != nil {
+		return fmt.Errorf("failed to persist transaction: %w", err)
+	}
+	return nil
+}
+
+// bump raises a fee by the configured replacement percentage
+func (t *TxManager) bump(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+t.feeBump))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// replayRevertReason re-executes a failed transaction at its block to recover the revert reason
+func (t *TxManager) replayRevertReason(ctx context.Context, tx *ethtypes.Transaction, blockNumber *big.Int) string {
+	msg := ethereum.CallMsg{
+		From:  t.from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	if _, err := t.client.CallContract(ctx, msg, blockNumber); err != nil {
+		return revertReason(err)
+	}
+
+	return "unknown reason"
+}
+
+// recordKey orders transaction records by nonce
+func recordKey(nonce uint64) string {
+	return fmt.Sprintf("%020d", nonce)
+}
+
+// decodeTx decodes a hex-encoded signed transaction
+func decodeTx(raw string) (*ethtypes.Transaction, error) {
+	data, err := hexutil.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored transaction: %w", err)
+	}
+
+	tx := new(ethtypes.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("invalid stored transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// isKnownTxError reports whether a send failed only because the node already has the transaction
+func isKnownTxError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a == nil || a.Cmp(b) < 0 {
+		return b
+	}
+	return a
+}
+
+// wrapTxError converts a revert surfaced during gas estimation or calls into a RevertError
+func wrapTxError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var revertErr *RevertError
+	if errors.As(err, &revertErr) {
+		return err
+	}
+
+	if !strings.Contains(err.Error(), "revert") {
+		return err
+	}
+
+	return &RevertError{Reason: revertReason(err)}
+}
+
+// revertReason extracts the Solidity revert string from an RPC error
+func revertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if raw, decodeErr := hexutil.Decode(data); decodeErr == nil {
+				if reason, unpackErr := abi.UnpackRevert(raw); unpackErr == nil {
+					return reason
+				}
+			}
+		}
+	}
+
+	return strings.TrimPrefix(err.Error(), "execution reverted: ")
+}
@@ -0,0 +1,324 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"dvpn-node/internal/blockchain/contracts"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	streamsBucket = "streams"
+	cursorsBucket = "cursors"
+
+	withdrawalCursor = "withdrawals"
+
+	// logChunkSize limits the block range of a single eth_getLogs request
+	logChunkSize = 5000
+)
+
+// trackedStream is a stream paying the node wallet that the scheduler watches
+type trackedStream struct {
+	StreamID string `json:"streamId"`
+	Sender   string `json:"sender"`
+	Block    uint64 `json:"block"`
+}
+
+// WithdrawalScheduler periodically withdraws earnings from every stream whose
+// recipient is the node wallet
+type WithdrawalScheduler struct {
+	blockchain    *BlockchainService
+	store         *store.Store
+	logger        *logrus.Logger
+	interval      time.Duration
+	threshold     *big.Int
+	endWindow     time.Duration
+	maxGasShare   *big.Float
+	gasTokenRate  *big.Float
+	startBlock    uint64
+	confirmations uint64
+	notify        func(types.WebSocketMessage)
+}
+
+// NewWithdrawalScheduler creates a withdrawal scheduler from the node configuration
+func NewWithdrawalScheduler(config *types.NodeConfig, blockchain *BlockchainService, db *store.Store, logger *logrus.Logger) (*WithdrawalScheduler, error) {
+	threshold, ok := new(big.Int).SetString(config.WithdrawThreshold, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid WITHDRAW_THRESHOLD: %s", config.WithdrawThreshold)
+	}
+
+	gasTokenRate, ok := new(big.Float).SetString(config.WithdrawGasTokenRate)
+	if !ok {
+		return nil, fmt.Errorf("invalid WITHDRAW_GAS_TOKEN_RATE: %s", config.WithdrawGasTokenRate)
+	}
+
+	return &WithdrawalScheduler{
+		blockchain:    blockchain,
+		store:         db,
+		logger:        logger,
+		interval:      config.WithdrawInterval,
+		threshold:     threshold,
+		endWindow:     config.WithdrawEndWindow,
+		maxGasShare:   big.NewFloat(config.WithdrawMaxGasShare),
+		gasTokenRate:  gasTokenRate,
+		startBlock:    config.WithdrawStartBlock,
+		confirmations: config.WithdrawConfirmations,
+		notify:        func(types.WebSocketMessage) {},
+	}, nil
+}
+
+// SetNotifier sets the function used to report withdrawals to WebSocket clients
+func (w *WithdrawalScheduler) SetNotifier(notify func(types.WebSocketMessage)) {
+	w.notify = notify
+}
+
+// Run discovers new streams and withdraws from them until ctx is cancelled
+func (w *WithdrawalScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.discoverStreams(ctx); err != nil {
+			w.logger.Errorf("Failed to discover payment streams: %v", err)
+		}
+
+		w.processStreams(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// discoverStreams scans StreamCreated events addressed to the node wallet
+// since the last scan. Only blocks with WITHDRAW_CONFIRMATIONS blocks on top
+// are scanned, so a reorg cannot move an event behind the cursor.
+func (w *WithdrawalScheduler) discoverStreams(ctx context.Context) error {
+	from := w.startBlock
+	var cursor uint64
+	found, err := w.store.Get(cursorsBucket, withdrawalCursor, &cursor)
+	if err != nil {
+		return err
+	}
+	if found {
+		from = cursor + 1
+	}
+
+	head, err := w.blockchain.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	if head < w.confirmations {
+		return nil
+	}
+	safe := head - w.confirmations
+
+	for start := from; start <= safe; start += logChunkSize {
+		end := min(start+logChunkSize-1, safe)
+
+		iter, err := w.blockchain.paymentHub.FilterStreamCreated(
+			&bind.FilterOpts{Start: start, End: &end, Context: ctx},
+			nil, nil, []common.Address{w.blockchain.walletAddress},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to filter StreamCreated events: %w", err)
+		}
+
+		for iter.Next() {
+			w.track(iter.Event)
+		}
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read StreamCreated events: %w", err)
+		}
+
+		if err := w.store.Put(cursorsBucket, withdrawalCursor, end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// track starts watching a newly discovered stream
+func (w *WithdrawalScheduler) track(event *contracts.PaymentHubStreamCreated) {
+	stream := trackedStream{
+		StreamID: hexutil.Encode(event.StreamId[:]),
+		Sender:   event.Sender.Hex(),
+		Block:    event.Raw.BlockNumber,
+	}
+
+	w.logger.Infof("Tracking payment stream %s from %s", stream.StreamID, stream.Sender)
+	if err := w.store.Put(streamsBucket, stream.StreamID, stream); err != nil {
+		w.logger.Errorf("Failed to track stream %s: %v", stream.StreamID, err)
+	}
+}
+
+// WithdrawAll withdraws everything currently claimable from every stream,
+// ignoring the threshold and gas limits. It is used when the node exits.
+func (w *WithdrawalScheduler) WithdrawAll(ctx context.Context) error {
+	if err := w.discoverStreams(ctx); err != nil {
+		return fmt.Errorf("failed to discover payment streams: %w", err)
+	}
+
+	streamIDs, err := w.streamIDs()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, streamID := range streamIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.processStream(ctx, streamID, true); err != nil {
+			errs = append(errs, fmt.Errorf("stream %s: %w", streamID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// processStreams checks every tracked stream and withdraws where worthwhile
+func (w *WithdrawalScheduler) processStreams(ctx context.Context) {
+	streamIDs, err := w.streamIDs()
+	if err != nil {
+		w.logger.Errorf("Failed to load tracked streams: %v", err)
+		return
+	}
+
+	for _, streamID := range streamIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.processStream(ctx, streamID, false); err != nil {
+			w.logger.Errorf("Failed to process stream %s: %v", streamID, err)
+		}
+	}
+}
+
+// streamIDs returns the IDs of all tracked streams
+func (w *WithdrawalScheduler) streamIDs() ([]string, error) {
+	var streamIDs []string
+	err := w.store.ForEach(streamsBucket, func(key string, _ []byte) error {
+		streamIDs = append(streamIDs, key)
+		return nil
+	})
+	return streamIDs, err
+}
+
+// processStream withdraws from a single stream if it passes the threshold or
+// is about to end, or unconditionally when force is set
+func (w *WithdrawalScheduler) processStream(ctx context.Context, streamID string, force bool) error {
+	stream, err := w.blockchain.GetStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	amount, _ := new(big.Int).SetString(stream.Amount, 10)
+	withdrawn, _ := new(big.Int).SetString(stream.Withdrawn, 10)
+	if !stream.IsActive || withdrawn.Cmp(amount) >= 0 {
+		w.logger.Infof("Stream %s is finished, no longer tracking", streamID)
+		return w.store.Delete(streamsBucket, streamID)
+	}
+
+	available, _ := new(big.Int).SetString(stream.Available, 10)
+	if available.Sign() == 0 {
+		return nil
+	}
+
+	endsAt := time.Unix(int64(stream.EndTime), 0)
+	nearEnd := time.Until(endsAt) <= w.endWindow
+
+	reason := "threshold"
+	switch {
+	case force:
+		reason = "node_exit"
+	case available.Cmp(w.threshold) < 0 && !nearEnd:
+		return nil
+	case nearEnd:
+		reason = "stream_ending"
+	}
+
+	if !force {
+		gasCost, err := w.estimateGasCost(ctx, streamID, available)
+		if err != nil {
+			return err
+		}
+
+		// Skip if gas would eat more than the configured share of the payout
+		maxCost := new(big.Float).Mul(new(big.Float).SetInt(available), w.maxGasShare)
+		if gasCost.Cmp(maxCost) > 0 {
+			w.logger.Infof("Skipping withdrawal of %s from stream %s: gas cost %s tokens exceeds limit",
+				available.String(), streamID, gasCost.Text('f', 0))
+			return nil
+		}
+	}
+
+	txHash, err := w.blockchain.WithdrawFromStream(streamID, available)
+	if err != nil {
+		return err
+	}
+
+	w.notify(types.WebSocketMessage{
+		Type: "stream_withdrawn",
+		Payload: map[string]interface{}{
+			"streamId": streamID,
+			"sender":   stream.Sender,
+			"amount":   available.String(),
+			"txHash":   txHash,
+			"reason":   reason,
+		},
+	})
+
+	return nil
+}
+
+// estimateGasCost estimates the cost of a withdrawal in token units
+func (w *WithdrawalScheduler) estimateGasCost(ctx context.Context, streamID string, amount *big.Int) (*big.Float, error) {
+	id, err := parseStreamID(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	hubABI, err := contracts.PaymentHubMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := hubABI.Pack("withdrawFromStream", id, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode withdrawFromStream: %w", err)
+	}
+
+	gas, err := w.blockchain.client.EstimateGas(ctx, ethereum.CallMsg{
+		From: w.blockchain.walletAddress,
+		To:   &w.blockchain.paymentHubAddr,
+		Data: input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", wrapTxError(err))
+	}
+
+	gasPrice, err := w.blockchain.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas price: %w", err)
+	}
+
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	return new(big.Float).Mul(new(big.Float).SetInt(cost), w.gasTokenRate), nil
+}
@@ -0,0 +1,150 @@
+package clientconfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"dvpn-node/internal/types"
+
+	"github.com/skip2/go-qrcode"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Tunnel modes
+const (
+	// TunnelFull routes all client traffic through the node
+	TunnelFull = "full"
+
+	// TunnelSplit routes only the tunnel subnet and CLIENT_SPLIT_ALLOWED_IPS
+	TunnelSplit = "split"
+)
+
+// Output formats
+const (
+	FormatJSON     = "json"
+	FormatConf     = "conf"
+	FormatPNG      = "png"
+	FormatTerminal = "terminal"
+)
+
+// privateKeyPlaceholder stands in for the client key, which the node never
+// stores, when the client did not supply it for rendering
+const privateKeyPlaceholder = "<client private key>"
+
+// qrSize is the width and height of PNG QR codes in pixels
+const qrSize = 512
+
+// ErrInvalidConfig is returned when a client configuration cannot be built
+var ErrInvalidConfig = errors.New("invalid client configuration")
+
+// Build returns the configuration a client needs to connect to the node with
+// the tunnel addresses leased to it
+func Build(config *types.NodeConfig, serverPublicKey string, addresses []string, tunnel string) (*types.ClientConfig, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: peer has no tunnel address", ErrInvalidConfig)
+	}
+	if config.NodePublicEndpoint == "" {
+		return nil, fmt.Errorf("%w: NODE_PUBLIC_ENDPOINT is not set", ErrInvalidConfig)
+	}
+
+	allowedIPs, err := AllowedIPs(config, tunnel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ClientConfig{
+		Address:             strings.Join(addresses, ", "),
+		DNS:                 config.ClientDNS,
+		ServerPublicKey:     serverPublicKey,
+		Endpoint:            Endpoint(config),
+		AllowedIPs:          allowedIPs,
+		PersistentKeepalive: config.ClientKeepalive,
+	}, nil
+}
+
+// Endpoint returns the host:port clients connect to. NODE_PUBLIC_ENDPOINT
+// may omit the port, in which case WG_PORT is used.
+func Endpoint(config *types.NodeConfig) string {
+	if _, _, err := net.SplitHostPort(config.NodePublicEndpoint); err == nil {
+		return config.NodePublicEndpoint
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(config.NodePublicEndpoint, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(config.WGPort))
+}
+
+// AllowedIPs returns the routes a client sends through the tunnel
+func AllowedIPs(config *types.NodeConfig, tunnel string) ([]string, error) {
+	switch tunnel {
+	case TunnelFull, "":
+		return []string{"0.0.0.0/0", "::/0"}, nil
+	case TunnelSplit:
+		_, subnet, err := net.ParseCIDR(config.WGSubnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WG_SUBNET: %w", err)
+		}
+		return append([]string{subnet.String()}, config.ClientSplitAllowedIPs...), nil
+	}
+	return nil, fmt.Errorf("%w: tunnel must be %q or %q", ErrInvalidConfig, TunnelFull, TunnelSplit)
+}
+
+// SetPrivateKey adds the client private key to config after checking that it
+// belongs to publicKey. The key is only used to render the configuration.
+func SetPrivateKey(config *types.ClientConfig, publicKey, privateKey string) error {
+	key, err := wgtypes.ParseKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("%w: invalid private key", ErrInvalidConfig)
+	}
+	if key.PublicKey().String() != publicKey {
+		return fmt.Errorf("%w: private key does not match the peer public key", ErrInvalidConfig)
+	}
+	config.PrivateKey = privateKey
+	return nil
+}
+
+// WGQuick renders config as a wg-quick configuration file
+func WGQuick(config *types.ClientConfig) string {
+	privateKey := config.PrivateKey
+	if privateKey == "" {
+		privateKey = privateKeyPlaceholder
+	}
+
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "PrivateKey = %s\n", privateKey)
+	fmt.Fprintf(&b, "Address = %s\n", config.Address)
+	if len(config.DNS) > 0 {
+		fmt.Fprintf(&b, "DNS = %s\n", strings.Join(config.DNS, ", "))
+	}
+
+	b.WriteString("\n[Peer]\n")
+	fmt.Fprintf(&b, "PublicKey = %s\n", config.ServerPublicKey)
+	fmt.Fprintf(&b, "Endpoint = %s\n", config.Endpoint)
+	fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(config.AllowedIPs, ", "))
+	if config.PersistentKeepalive > 0 {
+		fmt.Fprintf(&b, "PersistentKeepalive = %d\n", config.PersistentKeepalive)
+	}
+	return b.String()
+}
+
+// QRCodePNG renders the wg-quick file of config as a PNG QR code that the
+// WireGuard mobile apps can scan
+func QRCodePNG(config *types.ClientConfig) ([]byte, error) {
+	png, err := qrcode.Encode(WGQuick(config), qrcode.Medium, qrSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return png, nil
+}
+
+// QRCodeTerminal renders the wg-quick file of config as a QR code made of
+// block characters for display in a terminal
+func QRCodeTerminal(config *types.ClientConfig) (string, error) {
+	code, err := qrcode.New(WGQuick(config), qrcode.Medium)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return code.ToSmallString(false), nil
+}
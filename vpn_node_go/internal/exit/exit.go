@@ -0,0 +1,253 @@
+package exit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Exit stages, in order
+const (
+	StageDraining      = "draining"
+	StageWithdrawing   = "withdrawing"
+	StageUnregistering = "unregistering"
+	StageCompleted     = "completed"
+	StageFailed        = "failed"
+)
+
+const (
+	nodeBucket = "node"
+	exitKey    = "exit"
+)
+
+var (
+	// ErrExitInProgress is returned when an exit has already been started
+	ErrExitInProgress = errors.New("node exit already in progress")
+
+	// ErrAlreadyExited is returned when the node has already left the network
+	ErrAlreadyExited = errors.New("node has already exited")
+)
+
+// Registry unregisters the node wallet from NodeRegistry
+type Registry interface {
+	UnregisterNode() (string, error)
+}
+
+// Peers controls the WireGuard peers of the node
+type Peers interface {
+	SetAcceptingPeers(accepting bool)
+	RemoveAllPeers() error
+}
+
+// Withdrawer withdraws everything claimable from the node's payment streams
+type Withdrawer interface {
+	WithdrawAll(ctx context.Context) error
+}
+
+// Manager retires the node from the network: it stops accepting peers,
+// drains the existing ones after a grace period, withdraws every open
+// payment stream and finally unregisters the node, which refunds its stake.
+// Progress is persisted so an exit interrupted by a restart is resumed.
+type Manager struct {
+	blockchain  Registry
+	wireguard   Peers
+	withdrawals Withdrawer
+	store       *store.Store
+	logger      *logrus.Logger
+	notify      func(types.WebSocketMessage)
+	start       chan struct{}
+
+	mu     sync.Mutex
+	status *types.ExitStatus
+}
+
+// NewManager creates an exit manager and restores any exit in progress
+func NewManager(blockchain Registry, wireguard Peers, withdrawals Withdrawer, db *store.Store, logger *logrus.Logger) (*Manager, error) {
+	m := &Manager{
+		blockchain:  blockchain,
+		wireguard:   wireguard,
+		withdrawals: withdrawals,
+		store:       db,
+		logger:      logger,
+		notify:      func(types.WebSocketMessage) {},
+		start:       make(chan struct{}, 1),
+	}
+
+	var status types.ExitStatus
+	found, err := db.Get(nodeBucket, exitKey, &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exit status: %w", err)
+	}
+
+	if found {
+		m.status = &status
+		if status.Stage != StageFailed {
+			// Keep refusing peers across restarts once the node is leaving
+			wireguard.SetAcceptingPeers(false)
+		}
+		if status.Stage != StageFailed && status.Stage != StageCompleted {
+			logger.Infof("Resuming node exit at stage %s", status.Stage)
+			m.start <- struct{}{}
+		}
+	}
+
+	return m, nil
+}
+
+// SetNotifier sets the function used to report exit progress to WebSocket clients
+func (m *Manager) SetNotifier(notify func(types.WebSocketMessage)) {
+	m.notify = notify
+}
+
+// Status returns the current exit status, or nil if no exit was started
+func (m *Manager) Status() *types.ExitStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status == nil {
+		return nil
+	}
+	status := *m.status
+	return &status
+}
+
+// Start begins retiring the node. Existing peers are disconnected once
+// gracePeriod has passed. A failed exit can be started again.
+func (m *Manager) Start(gracePeriod time.Duration) (*types.ExitStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status != nil {
+		switch m.status.Stage {
+		case StageCompleted:
+			return nil, ErrAlreadyExited
+		case StageFailed:
+		default:
+			return nil, ErrExitInProgress
+		}
+	}
+
+	now := time.Now()
+	m.status = &types.ExitStatus{
+		Stage:         StageDraining,
+		StartedAt:     now.Unix(),
+		DrainDeadline: now.Add(gracePeriod).Unix(),
+	}
+	if err := m.store.Put(nodeBucket, exitKey, m.status); err != nil {
+		return nil, fmt.Errorf("failed to save exit status: %w", err)
+	}
+
+	m.wireguard.SetAcceptingPeers(false)
+	m.logger.Warnf("Node exit started, disconnecting peers at %s", time.Unix(m.status.DrainDeadline, 0).Format(time.RFC3339))
+
+	select {
+	case m.start <- struct{}{}:
+	default:
+	}
+
+	status := *m.status
+	return &status, nil
+}
+
+// Run performs exits as they are started until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.start:
+		}
+
+		if err := m.exit(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.logger.Errorf("Node exit failed: %v", err)
+			m.update(func(status *types.ExitStatus) {
+				status.Stage = StageFailed
+				status.Error = err.Error()
+			})
+		}
+	}
+}
+
+// exit runs the remaining stages of the current exit
+func (m *Manager) exit(ctx context.Context) error {
+	status := m.Status()
+	if status == nil {
+		return nil
+	}
+	m.notifyStatus(status)
+
+	if status.Stage == StageDraining {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(time.Unix(status.DrainDeadline, 0))):
+		}
+
+		if err := m.wireguard.RemoveAllPeers(); err != nil {
+			return err
+		}
+
+		status = m.update(func(status *types.ExitStatus) {
+			status.Stage = StageWithdrawing
+		})
+	}
+
+	if status.Stage == StageWithdrawing {
+		if err := m.withdrawals.WithdrawAll(ctx); err != nil {
+			return fmt.Errorf("failed to withdraw open streams: %w", err)
+		}
+
+		status = m.update(func(status *types.ExitStatus) {
+			status.Stage = StageUnregistering
+		})
+	}
+
+	if status.Stage == StageUnregistering {
+		txHash, err := m.blockchain.UnregisterNode()
+		if err != nil && !errors.Is(err, blockchain.ErrNodeInactive) {
+			return err
+		}
+
+		m.update(func(status *types.ExitStatus) {
+			status.Stage = StageCompleted
+			status.UnregisterTxHash = txHash
+		})
+		m.logger.Info("Node exit completed, stake reclaimed")
+	}
+
+	return nil
+}
+
+// update applies fn to the exit status, persists it and notifies clients
+func (m *Manager) update(fn func(status *types.ExitStatus)) *types.ExitStatus {
+	m.mu.Lock()
+	fn(m.status)
+	status := *m.status
+	m.mu.Unlock()
+
+	if err := m.store.Put(nodeBucket, exitKey, status); err != nil {
+		m.logger.Errorf("Failed to save exit status: %v", err)
+	}
+
+	m.notifyStatus(&status)
+	return &status
+}
+
+// notifyStatus reports the exit status to WebSocket clients
+func (m *Manager) notifyStatus(status *types.ExitStatus) {
+	m.notify(types.WebSocketMessage{
+		Type:    "node_exit",
+		Payload: status,
+	})
+}
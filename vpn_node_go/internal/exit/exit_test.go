@@ -0,0 +1,204 @@
+package exit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeNode records the exit steps performed on the node, in order
+type fakeNode struct {
+	mu          sync.Mutex
+	calls       []string
+	accepting   bool
+	withdrawErr error
+}
+
+func (f *fakeNode) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeNode) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeNode) SetAcceptingPeers(accepting bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accepting = accepting
+}
+
+func (f *fakeNode) RemoveAllPeers() error {
+	f.record("remove_peers")
+	return nil
+}
+
+func (f *fakeNode) WithdrawAll(ctx context.Context) error {
+	f.record("withdraw")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.withdrawErr
+	f.withdrawErr = nil
+	return err
+}
+
+func (f *fakeNode) UnregisterNode() (string, error) {
+	f.record("unregister")
+	return "0xabc", nil
+}
+
+func openTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "node.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestManager(t *testing.T, db *store.Store, node *fakeNode) *Manager {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	m, err := NewManager(node, node, node, db, logger)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+// run starts m.Run until the test ends
+func run(t *testing.T, m *Manager) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitForStage(t *testing.T, m *Manager, stage string) *types.ExitStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := m.Status(); status != nil && status.Stage == stage {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("exit did not reach stage %s, status: %+v", stage, m.Status())
+	return nil
+}
+
+func TestExitRunsStagesInOrder(t *testing.T) {
+	node := &fakeNode{accepting: true}
+	m := newTestManager(t, openTestStore(t), node)
+
+	var (
+		mu     sync.Mutex
+		stages []string
+	)
+	m.SetNotifier(func(message types.WebSocketMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		stages = append(stages, message.Payload.(*types.ExitStatus).Stage)
+	})
+	run(t, m)
+
+	if _, err := m.Start(0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := m.Start(0); !errors.Is(err, ErrExitInProgress) && !errors.Is(err, ErrAlreadyExited) {
+		t.Fatalf("second Start = %v, want ErrExitInProgress", err)
+	}
+
+	status := waitForStage(t, m, StageCompleted)
+	if status.UnregisterTxHash != "0xabc" {
+		t.Fatalf("unregister tx = %q, want 0xabc", status.UnregisterTxHash)
+	}
+	if node.accepting {
+		t.Fatal("node still accepts peers after exiting")
+	}
+	if want := []string{"remove_peers", "withdraw", "unregister"}; !reflect.DeepEqual(node.Calls(), want) {
+		t.Fatalf("calls = %v, want %v", node.Calls(), want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{StageDraining, StageWithdrawing, StageUnregistering, StageCompleted}; !reflect.DeepEqual(stages, want) {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+
+	if _, err := m.Start(0); !errors.Is(err, ErrAlreadyExited) {
+		t.Fatalf("Start after exiting = %v, want ErrAlreadyExited", err)
+	}
+}
+
+func TestExitResumesAfterRestart(t *testing.T) {
+	db := openTestStore(t)
+	if err := db.Put(nodeBucket, exitKey, types.ExitStatus{Stage: StageWithdrawing, StartedAt: 1}); err != nil {
+		t.Fatalf("failed to store exit status: %v", err)
+	}
+
+	node := &fakeNode{accepting: true}
+	m := newTestManager(t, db, node)
+	if node.accepting {
+		t.Fatal("node accepts peers while resuming an exit")
+	}
+	run(t, m)
+
+	waitForStage(t, m, StageCompleted)
+	if want := []string{"withdraw", "unregister"}; !reflect.DeepEqual(node.Calls(), want) {
+		t.Fatalf("calls = %v, want %v", node.Calls(), want)
+	}
+
+	var stored types.ExitStatus
+	if _, err := db.Get(nodeBucket, exitKey, &stored); err != nil || stored.Stage != StageCompleted {
+		t.Fatalf("stored stage = %q (%v), want %s", stored.Stage, err, StageCompleted)
+	}
+}
+
+func TestExitCanBeRetriedAfterFailure(t *testing.T) {
+	node := &fakeNode{accepting: true, withdrawErr: errors.New("rpc unavailable")}
+	m := newTestManager(t, openTestStore(t), node)
+	run(t, m)
+
+	if _, err := m.Start(0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := waitForStage(t, m, StageFailed)
+	if status.Error == "" {
+		t.Fatal("failed exit has no error")
+	}
+
+	if _, err := m.Start(0); err != nil {
+		t.Fatalf("Start after failure: %v", err)
+	}
+	waitForStage(t, m, StageCompleted)
+
+	want := []string{"remove_peers", "withdraw", "remove_peers", "withdraw", "unregister"}
+	if !reflect.DeepEqual(node.Calls(), want) {
+		t.Fatalf("calls = %v, want %v", node.Calls(), want)
+	}
+}
@@ -0,0 +1,150 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tableName is the nftables table holding every rule the node installs, so
+// that the rules can be replaced and removed as a whole
+const tableName = "dvpn"
+
+// ErrNotApplied is returned for the status of a firewall that has not been applied
+var ErrNotApplied = errors.New("firewall rules are not applied")
+
+// Manager installs the rules that let WireGuard peers use the node as an
+// exit: IPv4 forwarding, masquerading of WG_SUBNET out the egress interface,
+// forwarding between the tunnel and the egress interface, and isolation that
+// keeps peers from reaching each other and the node's admin API.
+type Manager struct {
+	config *types.NodeConfig
+	logger *logrus.Logger
+	subnet *net.IPNet
+	ports  []int
+
+	mu                 sync.Mutex
+	status             *types.FirewallStatus
+	previousForwarding string // ip_forward before the node enabled it
+}
+
+// NewManager creates a firewall manager from the node configuration
+func NewManager(config *types.NodeConfig, logger *logrus.Logger) (*Manager, error) {
+	_, subnet, err := net.ParseCIDR(config.WGSubnet)
+	if err != nil || subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid WG_SUBNET: %s", config.WGSubnet)
+	}
+
+	ports := []int{config.APIPort}
+	for _, entry := range config.FirewallBlockedPorts {
+		port, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid FIREWALL_BLOCKED_PORTS entry: %s", entry)
+		}
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	// Drop duplicates, e.g. API_PORT listed again
+	unique := ports[:0]
+	for _, port := range ports {
+		if len(unique) == 0 || port != unique[len(unique)-1] {
+			unique = append(unique, port)
+		}
+	}
+
+	return &Manager{
+		config: config,
+		logger: logger,
+		subnet: subnet,
+		ports:  unique,
+	}, nil
+}
+
+// Apply enables forwarding and installs the rules. It replaces any rules
+// left from a previous run, so it is safe to call again.
+func (m *Manager) Apply() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	egress := m.config.FirewallEgress
+	if egress == "" {
+		var err error
+		egress, err = defaultEgressInterface()
+		if err != nil {
+			return fmt.Errorf("failed to find the egress interface, set FIREWALL_EGRESS_INTERFACE: %w", err)
+		}
+	}
+
+	if err := m.installRules(egress); err != nil {
+		return fmt.Errorf("failed to install firewall rules: %w", err)
+	}
+
+	if m.status == nil {
+		previous, err := enableForwarding()
+		if err != nil {
+			if removeErr := m.removeRules(); removeErr != nil {
+				m.logger.Errorf("Failed to remove firewall rules: %v", removeErr)
+			}
+			return fmt.Errorf("failed to enable IPv4 forwarding: %w", err)
+		}
+		m.previousForwarding = previous
+	}
+
+	m.status = &types.FirewallStatus{
+		Table:           tableName,
+		Subnet:          m.subnet.String(),
+		EgressInterface: egress,
+		IsolatePeers:    m.config.FirewallIsolatePeers,
+		BlockedPorts:    m.ports,
+		Forwarding:      true,
+		AppliedAt:       time.Now().Unix(),
+	}
+
+	m.logger.Infof("Firewall applied: %s masqueraded out %s, peer isolation %t, blocked ports %v",
+		m.subnet, egress, m.config.FirewallIsolatePeers, m.ports)
+	return nil
+}
+
+// Cleanup removes the rules and restores the previous forwarding setting
+func (m *Manager) Cleanup() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status == nil {
+		return nil
+	}
+
+	var errs []error
+	if err := m.removeRules(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove firewall rules: %w", err))
+	}
+	if err := restoreForwarding(m.previousForwarding); err != nil {
+		errs = append(errs, fmt.Errorf("failed to restore IPv4 forwarding: %w", err))
+	}
+
+	m.status = nil
+	m.logger.Info("Firewall rules removed")
+	return errors.Join(errs...)
+}
+
+// Status returns the rules currently applied
+func (m *Manager) Status() (*types.FirewallStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status == nil {
+		return nil, ErrNotApplied
+	}
+	status := *m.status
+	return &status, nil
+}
@@ -0,0 +1,253 @@
+//go:build linux
+
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const ipForwardPath = "/proc/sys/net/ipv4/ip_forward"
+
+// installRules replaces the node's table with one built for egress. The old
+// table is deleted and the new one added in a single transaction, so traffic
+// never sees a half-built rule set.
+func (m *Manager) installRules(egress string) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	exists, err := tableExists(conn)
+	if err != nil {
+		return err
+	}
+
+	table := &nftables.Table{Name: tableName, Family: nftables.TableFamilyIPv4}
+	if exists {
+		conn.DelTable(table)
+	}
+	conn.AddTable(table)
+
+	accept := nftables.ChainPolicyAccept
+	forward := conn.AddChain(&nftables.Chain{
+		Name:     "forward",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &accept,
+	})
+	input := conn.AddChain(&nftables.Chain{
+		Name:     "input",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookInput,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &accept,
+	})
+	postrouting := conn.AddChain(&nftables.Chain{
+		Name:     "postrouting",
+		Table:    table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+	})
+
+	tunnel := m.config.WGInterface
+	var rules [][]expr.Any
+
+	// Peers cannot reach each other through the node
+	if m.config.FirewallIsolatePeers {
+		rules = append(rules, concat(inInterface(tunnel), outInterface(tunnel), verdict(expr.VerdictDrop)))
+	}
+
+	// Tunnel traffic may only go out the egress interface, and only replies
+	// may come back in
+	rules = append(rules,
+		concat(inInterface(tunnel), outInterface(egress), verdict(expr.VerdictAccept)),
+		concat(inInterface(egress), outInterface(tunnel), establishedOrRelated(), verdict(expr.VerdictAccept)),
+		concat(inInterface(tunnel), verdict(expr.VerdictDrop)),
+		concat(outInterface(tunnel), verdict(expr.VerdictDrop)),
+	)
+	for _, exprs := range rules {
+		conn.AddRule(&nftables.Rule{Table: table, Chain: forward, Exprs: exprs})
+	}
+
+	// Peers cannot reach the admin API or other blocked ports on the node
+	for _, port := range m.ports {
+		conn.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: input,
+			Exprs: concat(inInterface(tunnel), tcpDestinationPort(port), verdict(expr.VerdictDrop)),
+		})
+	}
+
+	conn.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: postrouting,
+		Exprs: concat(sourceNetwork(m.subnet), outInterface(egress), []expr.Any{&expr.Masq{}}),
+	})
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to commit nftables rules: %w", err)
+	}
+	return nil
+}
+
+// removeRules deletes the node's table if it exists
+func (m *Manager) removeRules() error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	exists, err := tableExists(conn)
+	if err != nil || !exists {
+		return err
+	}
+
+	conn.DelTable(&nftables.Table{Name: tableName, Family: nftables.TableFamilyIPv4})
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to delete nftables table: %w", err)
+	}
+	return nil
+}
+
+// tableExists reports whether the node's table is installed
+func tableExists(conn *nftables.Conn) (bool, error) {
+	tables, err := conn.ListTablesOfFamily(nftables.TableFamilyIPv4)
+	if err != nil {
+		return false, fmt.Errorf("failed to list nftables tables: %w", err)
+	}
+	for _, table := range tables {
+		if table.Name == tableName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// defaultEgressInterface returns the interface of the default IPv4 route
+func defaultEgressInterface() (string, error) {
+	routes, err := netlink.RouteGet(net.IPv4(1, 1, 1, 1))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the default route: %w", err)
+	}
+	if len(routes) == 0 {
+		return "", fmt.Errorf("no default route")
+	}
+
+	link, err := netlink.LinkByIndex(routes[0].LinkIndex)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the default route interface: %w", err)
+	}
+	return link.Attrs().Name, nil
+}
+
+// enableForwarding turns on IPv4 forwarding and returns the previous setting
+func enableForwarding() (string, error) {
+	data, err := os.ReadFile(ipForwardPath)
+	if err != nil {
+		return "", err
+	}
+
+	previous := strings.TrimSpace(string(data))
+	if previous == "1" {
+		return previous, nil
+	}
+	return previous, os.WriteFile(ipForwardPath, []byte("1\n"), 0644)
+}
+
+// restoreForwarding puts back the forwarding setting found by enableForwarding
+func restoreForwarding(previous string) error {
+	if previous == "" || previous == "1" {
+		return nil
+	}
+	return os.WriteFile(ipForwardPath, []byte(previous+"\n"), 0644)
+}
+
+// concat joins rule fragments
+func concat(parts ...[]expr.Any) []expr.Any {
+	var exprs []expr.Any
+	for _, part := range parts {
+		exprs = append(exprs, part...)
+	}
+	return exprs
+}
+
+// inInterface matches packets received on name
+func inInterface(name string) []expr.Any {
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: interfaceName(name)},
+	}
+}
+
+// outInterface matches packets sent out name
+func outInterface(name string) []expr.Any {
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: interfaceName(name)},
+	}
+}
+
+// establishedOrRelated matches packets of connections already accepted
+func establishedOrRelated() []expr.Any {
+	return []expr.Any{
+		&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            4,
+			Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED | expr.CtStateBitRELATED),
+			Xor:            binaryutil.NativeEndian.PutUint32(0),
+		},
+		&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(0)},
+	}
+}
+
+// sourceNetwork matches packets from an IPv4 network
+func sourceNetwork(network *net.IPNet) []expr.Any {
+	return []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 12, Len: 4},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            4,
+			Mask:           []byte(network.Mask),
+			Xor:            []byte{0, 0, 0, 0},
+		},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte(network.IP.To4())},
+	}
+}
+
+// tcpDestinationPort matches TCP packets to port
+func tcpDestinationPort(port int) []expr.Any {
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.IPPROTO_TCP}},
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(uint16(port))},
+	}
+}
+
+// verdict ends a rule with kind
+func verdict(kind expr.VerdictKind) []expr.Any {
+	return []expr.Any{&expr.Verdict{Kind: kind}}
+}
+
+// interfaceName pads name to the kernel's IFNAMSIZ
+func interfaceName(name string) []byte {
+	b := make([]byte, unix.IFNAMSIZ)
+	copy(b, name+"\x00")
+	return b
+}
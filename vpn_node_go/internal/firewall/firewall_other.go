@@ -0,0 +1,33 @@
+//go:build !linux
+
+package firewall
+
+import "errors"
+
+// errUnsupported is returned where the node cannot manage nftables
+var errUnsupported = errors.New("firewall management requires Linux")
+
+// installRules fails on platforms without nftables
+func (m *Manager) installRules(egress string) error {
+	return errUnsupported
+}
+
+// removeRules has nothing to remove on platforms without nftables
+func (m *Manager) removeRules() error {
+	return nil
+}
+
+// defaultEgressInterface fails on platforms without netlink
+func defaultEgressInterface() (string, error) {
+	return "", errUnsupported
+}
+
+// enableForwarding fails on platforms without nftables
+func enableForwarding() (string, error) {
+	return "", errUnsupported
+}
+
+// restoreForwarding has nothing to restore on platforms without nftables
+func restoreForwarding(previous string) error {
+	return nil
+}
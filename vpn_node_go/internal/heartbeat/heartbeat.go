@@ -0,0 +1,259 @@
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+	"dvpn-node/internal/wireguard"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	heartbeatBucket = "heartbeat"
+	stateKey        = "state"
+	pendingKey      = "pending"
+
+	// checkInterval is how often the WireGuard interface health is checked
+	checkInterval = 30 * time.Second
+
+	// maxPending caps the attestations kept while collectors are unreachable
+	maxPending = 500
+
+	// publishTimeout bounds a single request to a collector
+	publishTimeout = 10 * time.Second
+)
+
+// state is the persisted heartbeat sequence
+type state struct {
+	Sequence uint64 `json:"sequence"`
+}
+
+// Service proves the node is alive with signed attestations.
+//
+// NodeRegistry has no function a node can call to refresh its own
+// lastActive; only the registry owner updates it (updateReputation and
+// processPayment). The node therefore signs an off-chain attestation with
+// its wallet key each interval while its WireGuard interface is healthy, and
+// publishes them in batches to collectors (such as the registry owner's
+// reputation service) that can verify them and update the registry.
+type Service struct {
+	blockchain *blockchain.BlockchainService
+	wireguard  *wireguard.WireGuardService
+	store      *store.Store
+	logger     *logrus.Logger
+	interval   time.Duration
+	batchSize  int
+	urls       []string
+	client     *http.Client
+
+	mu     sync.Mutex
+	status types.HeartbeatStatus
+}
+
+// NewService creates a heartbeat service from the node configuration
+func NewService(config *types.NodeConfig, blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService, db *store.Store, logger *logrus.Logger) *Service {
+	batchSize := config.HeartbeatBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &Service{
+		blockchain: blockchain,
+		wireguard:  wireguard,
+		store:      db,
+		logger:     logger,
+		interval:   config.HeartbeatInterval,
+		batchSize:  batchSize,
+		urls:       config.HeartbeatURLs,
+		client:     &http.Client{Timeout: publishTimeout},
+	}
+}
+
+// Message returns the text a heartbeat signature covers. Collectors verify a
+// heartbeat by recovering the EIP-191 signer of this message.
+func Message(h *types.Heartbeat) []byte {
+	return []byte(fmt.Sprintf(
+		"dVPN heartbeat\nnode: %s\nchain: %s\nregistry: %s\nsequence: %d\ntimestamp: %d\npeers: %d\nrx: %d\ntx: %d",
+		h.Node, h.ChainID, h.Registry, h.Sequence, h.Timestamp, h.ConnectedPeers, h.BytesRx, h.BytesTx,
+	))
+}
+
+// Status returns the current heartbeat status
+func (s *Service) Status() types.HeartbeatStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	if pending, err := s.Pending(); err == nil {
+		status.Pending = len(pending)
+	}
+	return status
+}
+
+// Run checks the interface and emits heartbeats until ctx is cancelled. A
+// heartbeat is due once per interval, but only while the interface is
+// healthy; after an outage the first healthy check emits one immediately.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		healthy := s.check()
+
+		if healthy && time.Since(last) >= s.interval {
+			if err := s.beat(ctx); err != nil {
+				s.logger.Errorf("Failed to send heartbeat: %v", err)
+			} else {
+				last = time.Now()
+			}
+		}
+		if !healthy {
+			// Resume with a fresh heartbeat as soon as the tunnel recovers
+			last = time.Time{}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check refreshes peer stats from the interface and records whether it is healthy
+func (s *Service) check() bool {
+	err := s.wireguard.UpdatePeerStats()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		if s.status.Healthy || s.status.UnhealthySince == 0 {
+			s.status.UnhealthySince = time.Now().Unix()
+			s.logger.Warnf("WireGuard interface unhealthy, pausing heartbeats: %v", err)
+		}
+		s.status.Healthy = false
+		s.status.LastError = err.Error()
+		return false
+	}
+
+	if !s.status.Healthy && s.status.UnhealthySince != 0 {
+		s.logger.Info("WireGuard interface healthy again, resuming heartbeats")
+	}
+	s.status.Healthy = true
+	s.status.UnhealthySince = 0
+	s.status.LastError = ""
+	return true
+}
+
+// beat signs a heartbeat, queues it and publishes the batch once it is full
+func (s *Service) beat(ctx context.Context) error {
+	var st state
+	if _, err := s.store.Get(heartbeatBucket, stateKey, &st); err != nil {
+		return err
+	}
+
+	rx, tx := s.wireguard.GetTotalBandwidth()
+	heartbeat := types.Heartbeat{
+		Node:           s.blockchain.GetWalletAddress(),
+		ChainID:        s.blockchain.ChainID().String(),
+		Registry:       s.blockchain.NodeRegistryAddress(),
+		Sequence:       st.Sequence + 1,
+		Timestamp:      time.Now().Unix(),
+		ConnectedPeers: s.wireguard.GetConnectedPeersCount(),
+		BytesRx:        rx,
+		BytesTx:        tx,
+	}
+
+	signature, err := s.blockchain.SignMessage(Message(&heartbeat))
+	if err != nil {
+		return fmt.Errorf("failed to sign heartbeat: %w", err)
+	}
+	heartbeat.Signature = hexutil.Encode(signature)
+
+	st.Sequence = heartbeat.Sequence
+	if err := s.store.Put(heartbeatBucket, stateKey, st); err != nil {
+		return err
+	}
+
+	pending, err := s.Pending()
+	if err != nil {
+		return err
+	}
+	pending = append(pending, heartbeat)
+	if len(pending) > maxPending {
+		pending = pending[len(pending)-maxPending:]
+	}
+	if err := s.store.Put(heartbeatBucket, pendingKey, pending); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.status.LastHeartbeat = &heartbeat
+	s.mu.Unlock()
+
+	s.logger.Debugf("Heartbeat %d signed", heartbeat.Sequence)
+
+	if len(pending) < s.batchSize || len(s.urls) == 0 {
+		return nil
+	}
+
+	if err := s.publish(ctx, pending); err != nil {
+		s.logger.Warnf("Failed to publish %d heartbeats, will retry: %v", len(pending), err)
+		return nil
+	}
+
+	return s.store.Delete(heartbeatBucket, pendingKey)
+}
+
+// publish posts a batch of heartbeats to every collector
+func (s *Service) publish(ctx context.Context, heartbeats []types.Heartbeat) error {
+	body, err := json.Marshal(types.HeartbeatBatch{
+		Node:       s.blockchain.GetWalletAddress(),
+		Heartbeats: heartbeats,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode heartbeats: %w", err)
+	}
+
+	for _, url := range s.urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("invalid collector request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("collector %s returned status %d", url, resp.StatusCode)
+		}
+	}
+
+	s.logger.Infof("Published %d heartbeats", len(heartbeats))
+	return nil
+}
+
+// Pending returns the signed heartbeats not yet published, oldest first
+func (s *Service) Pending() ([]types.Heartbeat, error) {
+	var pending []types.Heartbeat
+	if _, err := s.store.Get(heartbeatBucket, pendingKey, &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
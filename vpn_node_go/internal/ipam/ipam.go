@@ -0,0 +1,317 @@
+package ipam
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const leasesBucket = "ipam_leases"
+
+var (
+	// ErrInvalidAddress is returned for addresses that cannot be leased to a peer
+	ErrInvalidAddress = errors.New("invalid tunnel address")
+
+	// ErrAddressInUse is returned when an address is leased to another peer
+	ErrAddressInUse = errors.New("tunnel address already in use")
+
+	// ErrPoolExhausted is returned when every address in the subnet is leased
+	ErrPoolExhausted = errors.New("no tunnel address available")
+)
+
+// Allocator hands out tunnel addresses from WG_SUBNET. Every peer holds a
+// lease on one or more /32 host addresses; leases are unique, never include
+// the server address and are persisted so they survive restarts.
+type Allocator struct {
+	store   *store.Store
+	logger  *logrus.Logger
+	subnet  *net.IPNet
+	server  net.IP
+	base    uint32
+	size    uint32
+	mu      sync.Mutex
+	leases  map[string]*types.IPLease // by peer public key
+	holders map[string]string         // address -> peer public key
+}
+
+// NewAllocator creates an allocator for the node subnet and loads its leases
+func NewAllocator(config *types.NodeConfig, db *store.Store, logger *logrus.Logger) (*Allocator, error) {
+	server, subnet, err := net.ParseCIDR(config.WGSubnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WG_SUBNET: %w", err)
+	}
+	if server.To4() == nil {
+		return nil, fmt.Errorf("invalid WG_SUBNET: %s is not an IPv4 subnet", config.WGSubnet)
+	}
+
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("invalid WG_SUBNET: %s has no room for peers", config.WGSubnet)
+	}
+
+	a := &Allocator{
+		store:   db,
+		logger:  logger,
+		subnet:  subnet,
+		server:  server.To4(),
+		base:    binary.BigEndian.Uint32(subnet.IP.To4()),
+		size:    uint32(1) << uint(bits-ones),
+		leases:  make(map[string]*types.IPLease),
+		holders: make(map[string]string),
+	}
+
+	err = db.ForEach(leasesBucket, func(_ string, value []byte) error {
+		var lease types.IPLease
+		if err := json.Unmarshal(value, &lease); err != nil {
+			return err
+		}
+		a.leases[lease.PublicKey] = &lease
+		for _, address := range lease.Addresses {
+			a.holders[address] = lease.PublicKey
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load address leases: %w", err)
+	}
+
+	logger.Infof("Address pool %s: %d of %d addresses leased", subnet, len(a.holders), a.capacity())
+	return a, nil
+}
+
+// Allocate returns the addresses leased to a peer, leasing a free address
+// if it has none
+func (a *Allocator) Allocate(publicKey string) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if lease, exists := a.leases[publicKey]; exists {
+		return append([]string(nil), lease.Addresses...), nil
+	}
+
+	for offset := uint32(1); offset < a.size-1; offset++ {
+		address := a.address(offset)
+		if address.Equal(a.server) {
+			continue
+		}
+		cidr := address.String() + "/32"
+		if _, used := a.holders[cidr]; used {
+			continue
+		}
+
+		if err := a.lease(publicKey, []string{cidr}); err != nil {
+			return nil, err
+		}
+		return []string{cidr}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s is exhausted", ErrPoolExhausted, a.subnet)
+}
+
+// Reserve leases the caller-supplied addresses to a peer, replacing any
+// lease it already holds. Each address must be a /32 host address in the
+// subnet that is not the server address or leased to another peer.
+func (a *Allocator) Reserve(publicKey string, addresses []string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: no addresses given", ErrInvalidAddress)
+	}
+
+	normalized := make([]string, 0, len(addresses))
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		cidr, err := a.normalize(address)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[cidr] {
+			seen[cidr] = true
+			normalized = append(normalized, cidr)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, cidr := range normalized {
+		if holder, used := a.holders[cidr]; used && holder != publicKey {
+			return nil, fmt.Errorf("%w: %s is leased to %s", ErrAddressInUse, cidr, holder)
+		}
+	}
+
+	if err := a.lease(publicKey, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// Release frees the addresses leased to a peer
+func (a *Allocator) Release(publicKey string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lease, exists := a.leases[publicKey]
+	if !exists {
+		return nil
+	}
+
+	if err := a.store.Delete(leasesBucket, publicKey); err != nil {
+		return err
+	}
+
+	for _, address := range lease.Addresses {
+		delete(a.holders, address)
+	}
+	delete(a.leases, publicKey)
+	return nil
+}
+
+// Lease returns the lease of a peer
+func (a *Allocator) Lease(publicKey string) (*types.IPLease, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lease, exists := a.leases[publicKey]
+	if !exists {
+		return nil, false
+	}
+	copied := *lease
+	copied.Addresses = append([]string(nil), lease.Addresses...)
+	return &copied, true
+}
+
+// Leases returns every lease ordered by address
+func (a *Allocator) Leases() []types.IPLease {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	leases := make([]types.IPLease, 0, len(a.leases))
+	for _, lease := range a.leases {
+		copied := *lease
+		copied.Addresses = append([]string(nil), lease.Addresses...)
+		leases = append(leases, copied)
+	}
+
+	sort.Slice(leases, func(i, j int) bool {
+		return a.order(leases[i].Addresses) < a.order(leases[j].Addresses)
+	})
+	return leases
+}
+
+// Stats returns the utilisation of the address pool
+func (a *Allocator) Stats() types.IPPoolStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	total := a.capacity()
+	used := len(a.holders)
+
+	stats := types.IPPoolStats{
+		Subnet:        a.subnet.String(),
+		ServerAddress: a.server.String(),
+		Total:         total,
+		Used:          used,
+		Available:     total - used,
+		Peers:         len(a.leases),
+	}
+	if total > 0 {
+		stats.Utilization = float64(used) / float64(total)
+	}
+	return stats
+}
+
+// lease stores the addresses of a peer, replacing its previous lease.
+// Callers must hold a.mu.
+func (a *Allocator) lease(publicKey string, addresses []string) error {
+	lease := types.IPLease{
+		PublicKey:  publicKey,
+		Addresses:  addresses,
+		AssignedAt: time.Now().Unix(),
+	}
+	if previous, exists := a.leases[publicKey]; exists {
+		lease.AssignedAt = previous.AssignedAt
+	}
+
+	if err := a.store.Put(leasesBucket, publicKey, lease); err != nil {
+		return err
+	}
+
+	if previous, exists := a.leases[publicKey]; exists {
+		for _, address := range previous.Addresses {
+			delete(a.holders, address)
+		}
+	}
+	for _, address := range addresses {
+		a.holders[address] = publicKey
+	}
+	a.leases[publicKey] = &lease
+	return nil
+}
+
+// normalize checks that address can be leased and returns it in a.b.c.d/32 form
+func (a *Allocator) normalize(address string) (string, error) {
+	ip, ipNet, err := net.ParseCIDR(address)
+	if err != nil {
+		// Accept bare addresses as /32
+		ip = net.ParseIP(address)
+		if ip == nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+		}
+		ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}
+	}
+
+	ip = ip.To4()
+	if ones, bits := ipNet.Mask.Size(); ip == nil || ones != 32 || bits != 32 {
+		return "", fmt.Errorf("%w: %s must be a single IPv4 host (/32)", ErrInvalidAddress, address)
+	}
+	if !a.subnet.Contains(ip) {
+		return "", fmt.Errorf("%w: %s is outside %s", ErrInvalidAddress, address, a.subnet)
+	}
+
+	offset := binary.BigEndian.Uint32(ip) - a.base
+	if offset == 0 || offset == a.size-1 {
+		return "", fmt.Errorf("%w: %s is the network or broadcast address", ErrInvalidAddress, address)
+	}
+	if ip.Equal(a.server) {
+		return "", fmt.Errorf("%w: %s is the server address", ErrInvalidAddress, address)
+	}
+
+	return ip.String() + "/32", nil
+}
+
+// address returns the address at offset in the subnet
+func (a *Allocator) address(offset uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, a.base+offset)
+	return ip
+}
+
+// capacity returns the number of addresses peers can lease
+func (a *Allocator) capacity() int {
+	total := int(a.size) - 2 // network and broadcast
+	if offset := binary.BigEndian.Uint32(a.server) - a.base; a.subnet.Contains(a.server) && offset != 0 && offset != a.size-1 {
+		total--
+	}
+	return total
+}
+
+// order returns the sort key of a lease
+func (a *Allocator) order(addresses []string) uint32 {
+	if len(addresses) == 0 {
+		return 0
+	}
+	ip, _, err := net.ParseCIDR(addresses[0])
+	if err != nil || ip.To4() == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(ip.To4())
+}
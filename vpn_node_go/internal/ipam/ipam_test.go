@@ -0,0 +1,256 @@
+package ipam
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func openTestStore(t *testing.T, path string) *store.Store {
+	t.Helper()
+	db, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	return db
+}
+
+func newTestAllocator(t *testing.T, subnet string) *Allocator {
+	t.Helper()
+	db := openTestStore(t, filepath.Join(t.TempDir(), "node.db"))
+	t.Cleanup(func() { db.Close() })
+
+	allocator, err := NewAllocator(&types.NodeConfig{WGSubnet: subnet}, db, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewAllocator(%s): %v", subnet, err)
+	}
+	return allocator
+}
+
+func TestNewAllocator(t *testing.T) {
+	tests := []struct {
+		subnet   string
+		wantErr  bool
+		capacity int
+	}{
+		{subnet: "10.8.0.1/24", capacity: 253},
+		{subnet: "10.8.0.1/30", capacity: 1},
+		{subnet: "10.8.0.0/24", capacity: 254}, // server on the network address takes no host
+		{subnet: "10.8.0.1/31", wantErr: true},
+		{subnet: "10.8.0.1/32", wantErr: true},
+		{subnet: "fd00::1/64", wantErr: true},
+		{subnet: "not-a-subnet", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subnet, func(t *testing.T) {
+			db := openTestStore(t, filepath.Join(t.TempDir(), "node.db"))
+			defer db.Close()
+
+			allocator, err := NewAllocator(&types.NodeConfig{WGSubnet: tt.subnet}, db, newTestLogger())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewAllocator succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewAllocator: %v", err)
+			}
+			if got := allocator.Stats().Total; got != tt.capacity {
+				t.Fatalf("Total = %d, want %d", got, tt.capacity)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	allocator := newTestAllocator(t, "10.8.0.1/24")
+
+	first, err := allocator.Allocate("peer-a")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if len(first) != 1 || first[0] != "10.8.0.2/32" {
+		t.Fatalf("first lease = %v, want [10.8.0.2/32] (skipping the server address)", first)
+	}
+
+	again, err := allocator.Allocate("peer-a")
+	if err != nil || len(again) != 1 || again[0] != first[0] {
+		t.Fatalf("Allocate again = %v, %v; want the existing lease %v", again, err, first)
+	}
+
+	second, err := allocator.Allocate("peer-b")
+	if err != nil || second[0] != "10.8.0.3/32" {
+		t.Fatalf("second lease = %v, %v; want [10.8.0.3/32]", second, err)
+	}
+
+	if err := allocator.Release("peer-a"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	reused, err := allocator.Allocate("peer-c")
+	if err != nil || reused[0] != first[0] {
+		t.Fatalf("lease after release = %v, %v; want the freed %v", reused, err, first)
+	}
+}
+
+func TestAllocateExhaustion(t *testing.T) {
+	// 10.8.0.0/29 has six hosts, one of which is the server
+	allocator := newTestAllocator(t, "10.8.0.1/29")
+
+	leased := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		addresses, err := allocator.Allocate(fmt.Sprintf("peer-%d", i))
+		if err != nil {
+			t.Fatalf("Allocate %d: %v", i, err)
+		}
+		switch addresses[0] {
+		case "10.8.0.0/32", "10.8.0.1/32", "10.8.0.7/32":
+			t.Fatalf("leased reserved address %s", addresses[0])
+		}
+		if leased[addresses[0]] {
+			t.Fatalf("address %s leased twice", addresses[0])
+		}
+		leased[addresses[0]] = true
+	}
+
+	if _, err := allocator.Allocate("peer-5"); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("Allocate on a full pool = %v, want ErrPoolExhausted", err)
+	}
+	if stats := allocator.Stats(); stats.Available != 0 || stats.Used != 5 {
+		t.Fatalf("stats = %+v, want 5 used and none available", stats)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		want      []string
+		wantErr   error
+	}{
+		{name: "host", addresses: []string{"10.8.0.10/32"}, want: []string{"10.8.0.10/32"}},
+		{name: "bare address", addresses: []string{"10.8.0.10"}, want: []string{"10.8.0.10/32"}},
+		{name: "duplicates", addresses: []string{"10.8.0.10/32", "10.8.0.10"}, want: []string{"10.8.0.10/32"}},
+		{name: "several", addresses: []string{"10.8.0.10/32", "10.8.0.11/32"}, want: []string{"10.8.0.10/32", "10.8.0.11/32"}},
+		{name: "empty", addresses: nil, wantErr: ErrInvalidAddress},
+		{name: "garbage", addresses: []string{"nope"}, wantErr: ErrInvalidAddress},
+		{name: "network", addresses: []string{"10.8.0.0/32"}, wantErr: ErrInvalidAddress},
+		{name: "broadcast", addresses: []string{"10.8.0.255/32"}, wantErr: ErrInvalidAddress},
+		{name: "server", addresses: []string{"10.8.0.1/32"}, wantErr: ErrInvalidAddress},
+		{name: "outside subnet", addresses: []string{"10.9.0.10/32"}, wantErr: ErrInvalidAddress},
+		{name: "not a host", addresses: []string{"10.8.0.8/29"}, wantErr: ErrInvalidAddress},
+		{name: "ipv6", addresses: []string{"fd00::10/128"}, wantErr: ErrInvalidAddress},
+		{name: "leased to another peer", addresses: []string{"10.8.0.20/32"}, wantErr: ErrAddressInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator := newTestAllocator(t, "10.8.0.1/24")
+			if _, err := allocator.Reserve("other", []string{"10.8.0.20/32"}); err != nil {
+				t.Fatalf("Reserve for other peer: %v", err)
+			}
+
+			got, err := allocator.Reserve("peer", tt.addresses)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Reserve(%v) = %v, want %v", tt.addresses, err, tt.wantErr)
+				}
+				if _, leased := allocator.Lease("peer"); leased {
+					t.Fatal("failed Reserve left a lease behind")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reserve(%v): %v", tt.addresses, err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Reserve(%v) = %v, want %v", tt.addresses, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveReplacesLease(t *testing.T) {
+	allocator := newTestAllocator(t, "10.8.0.1/24")
+
+	if _, err := allocator.Reserve("peer", []string{"10.8.0.10/32"}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := allocator.Reserve("peer", []string{"10.8.0.11/32"}); err != nil {
+		t.Fatalf("Reserve again: %v", err)
+	}
+
+	// The old address is free again
+	if _, err := allocator.Reserve("other", []string{"10.8.0.10/32"}); err != nil {
+		t.Fatalf("Reserve of the replaced address: %v", err)
+	}
+	if stats := allocator.Stats(); stats.Used != 2 || stats.Peers != 2 {
+		t.Fatalf("stats = %+v, want 2 addresses used by 2 peers", stats)
+	}
+}
+
+func TestLeasesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	config := &types.NodeConfig{WGSubnet: "10.8.0.1/24"}
+
+	db := openTestStore(t, path)
+	allocator, err := NewAllocator(config, db, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewAllocator: %v", err)
+	}
+	allocated, err := allocator.Allocate("peer-a")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if _, err := allocator.Reserve("peer-b", []string{"10.8.0.50/32"}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := allocator.Allocate("peer-c"); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if err := allocator.Release("peer-c"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	db.Close()
+
+	db = openTestStore(t, path)
+	defer db.Close()
+	reloaded, err := NewAllocator(config, db, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewAllocator after restart: %v", err)
+	}
+
+	leases := reloaded.Leases()
+	if len(leases) != 2 {
+		t.Fatalf("reloaded %d leases, want 2: %+v", len(leases), leases)
+	}
+	if leases[0].PublicKey != "peer-a" || leases[0].Addresses[0] != allocated[0] {
+		t.Fatalf("first lease = %+v, want peer-a on %s", leases[0], allocated[0])
+	}
+	if leases[1].PublicKey != "peer-b" || leases[1].Addresses[0] != "10.8.0.50/32" {
+		t.Fatalf("second lease = %+v, want peer-b on 10.8.0.50/32", leases[1])
+	}
+
+	// Reloaded leases are still held
+	if _, err := reloaded.Reserve("peer-d", []string{"10.8.0.50/32"}); !errors.Is(err, ErrAddressInUse) {
+		t.Fatalf("Reserve of a reloaded address = %v, want ErrAddressInUse", err)
+	}
+	next, err := reloaded.Allocate("peer-d")
+	if err != nil || next[0] != "10.8.0.3/32" {
+		t.Fatalf("Allocate after restart = %v, %v; want the released 10.8.0.3/32", next, err)
+	}
+}
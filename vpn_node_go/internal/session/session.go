@@ -0,0 +1,452 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/billing"
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/clientconfig"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+	"dvpn-node/internal/wireguard"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const sessionsBucket = "sessions"
+
+// maxRequestLifetime bounds how far in the future a signed session request
+// may expire, so a leaked signature cannot be replayed indefinitely
+const maxRequestLifetime = time.Hour
+
+// Reasons a session ends
+const (
+	ReasonStreamEnded    = "stream_ended"
+	ReasonStreamInactive = "stream_inactive"
+	ReasonPeerRemoved    = "peer_removed"
+	ReasonClosed         = "closed"
+
+	// ReasonPaymentExpired is reported to the peer reaper once a session's
+	// stream has reached its end time
+	ReasonPaymentExpired = "payment_expired"
+)
+
+var (
+	// ErrInvalidSession is returned when a session request cannot be accepted
+	ErrInvalidSession = errors.New("invalid session request")
+
+	// ErrSessionNotFound is returned when a peer has no session
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSessionExists is returned when a peer or stream already has a session
+	ErrSessionExists = errors.New("session already exists")
+
+	// ErrInvalidSignature is returned when a session request is not signed by
+	// the sender of its stream
+	ErrInvalidSignature = errors.New("invalid session signature")
+)
+
+// Manager turns a funded payment stream into a WireGuard connection. A
+// client submits its public key, payer address and PaymentHub stream, signed
+// by the payer; once the signature and the stream check out the node
+// allocates a tunnel address, adds the peer, starts billing it and hands back
+// a client configuration. Sessions are torn down when their stream ends or is
+// cancelled.
+type Manager struct {
+	config     *types.NodeConfig
+	blockchain *blockchain.BlockchainService
+	wireguard  *wireguard.WireGuardService
+	billing    *billing.Engine
+	store      *store.Store
+	logger     *logrus.Logger
+	minAmount  *big.Int
+	notify     func(types.WebSocketMessage)
+
+	mu sync.Mutex
+}
+
+// NewManager creates a session manager from the node configuration
+func NewManager(config *types.NodeConfig, blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService, billing *billing.Engine, db *store.Store, logger *logrus.Logger) (*Manager, error) {
+	minAmount, ok := new(big.Int).SetString(config.SessionMinAmount, 10)
+	if !ok || minAmount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid SESSION_MIN_AMOUNT: %s", config.SessionMinAmount)
+	}
+
+	return &Manager{
+		config:     config,
+		blockchain: blockchain,
+		wireguard:  wireguard,
+		billing:    billing,
+		store:      db,
+		logger:     logger,
+		minAmount:  minAmount,
+		notify:     func(types.WebSocketMessage) {},
+	}, nil
+}
+
+// SetNotifier sets the function used to report session events to WebSocket clients
+func (m *Manager) SetNotifier(notify func(types.WebSocketMessage)) {
+	m.notify = notify
+}
+
+// Create verifies the stream paying for a client and the payer's signature
+// of the request, and connects the client. signature is the EIP-191 signature
+// of RequestMessage, which expires at expiry (Unix seconds).
+func (m *Manager) Create(publicKey, payer, streamID string, expiry int64, signature string) (*types.Session, *types.ClientConfig, error) {
+	if _, err := wgtypes.ParseKey(publicKey); err != nil {
+		return nil, nil, fmt.Errorf("%w: publicKey must be a WireGuard public key", ErrInvalidSession)
+	}
+	if !common.IsHexAddress(payer) {
+		return nil, nil, fmt.Errorf("%w: payer must be an address", ErrInvalidSession)
+	}
+
+	stream, err := m.blockchain.GetStream(streamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.checkStream(stream, payer); err != nil {
+		return nil, nil, err
+	}
+
+	// Stream IDs and senders are public, so only the sender's signature proves
+	// the client may spend the stream
+	message := RequestMessage(publicKey, stream.StreamID, m.blockchain.GetWalletAddress(), expiry)
+	if err := verifyRequest(message, signature, stream.Sender, expiry, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions, err := m.sessions()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, session := range sessions {
+		if session.PublicKey == publicKey {
+			return nil, nil, fmt.Errorf("%w: peer %s is already connected", ErrSessionExists, publicKey)
+		}
+		if strings.EqualFold(session.StreamID, stream.StreamID) {
+			return nil, nil, fmt.Errorf("%w: stream %s already pays for a session", ErrSessionExists, stream.StreamID)
+		}
+	}
+	if _, exists := m.wireguard.GetPeer(publicKey); exists {
+		return nil, nil, fmt.Errorf("%w: peer %s is already connected", ErrSessionExists, publicKey)
+	}
+
+	addresses, err := m.wireguard.AddPeer(publicKey, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	address := addresses[0]
+
+	if _, err := m.billing.Attach(publicKey, payer, stream.StreamID); err != nil {
+		m.removePeer(publicKey)
+		return nil, nil, fmt.Errorf("failed to start billing: %w", err)
+	}
+
+	session := types.Session{
+		PublicKey: publicKey,
+		Payer:     common.HexToAddress(payer).Hex(),
+		StreamID:  stream.StreamID,
+		Address:   address,
+		CreatedAt: time.Now().Unix(),
+		EndsAt:    int64(stream.EndTime),
+	}
+
+	config, err := clientconfig.Build(m.config, m.wireguard.GetPublicKey(), addresses, clientconfig.TunnelFull)
+	if err == nil {
+		err = m.store.Put(sessionsBucket, publicKey, session)
+	}
+	if err != nil {
+		m.billing.Detach(publicKey)
+		m.removePeer(publicKey)
+		return nil, nil, err
+	}
+
+	m.logger.Infof("Session started for peer %s at %s, paid by %s via stream %s", publicKey, address, session.Payer, session.StreamID)
+	m.notify(types.WebSocketMessage{
+		Type:    "session_started",
+		Payload: session,
+	})
+
+	return &session, config, nil
+}
+
+// Close ends the session of a peer at the request of its payer. signature is
+// the payer's EIP-191 signature of CloseMessage, which expires at expiry.
+func (m *Manager) Close(publicKey string, expiry int64, signature string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, err := m.session(publicKey)
+	if err != nil {
+		return err
+	}
+
+	// Peer keys are public, so only the payer's signature proves the
+	// request comes from the client
+	message := CloseMessage(publicKey, session.StreamID, m.blockchain.GetWalletAddress(), expiry)
+	if err := verifyRequest(message, signature, session.Payer, expiry, time.Now()); err != nil {
+		return err
+	}
+	return m.end(session, ReasonClosed)
+}
+
+// Session returns the session of a peer
+func (m *Manager) Session(publicKey string) (*types.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.session(publicKey)
+}
+
+// Sessions returns every open session
+func (m *Manager) Sessions() ([]types.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sessions()
+}
+
+// Expired reports whether the stream paying for a peer's session has
+// reached its end time. Peers without a session never expire here.
+func (m *Manager) Expired(publicKey string) (string, bool) {
+	session, err := m.Session(publicKey)
+	if err != nil {
+		return "", false
+	}
+	if session.EndsAt > 0 && time.Now().Unix() >= session.EndsAt {
+		return ReasonPaymentExpired, true
+	}
+	return "", false
+}
+
+// Peers returns the tunnel addresses of every open session by peer public key
+func (m *Manager) Peers() (map[string][]string, error) {
+	sessions, err := m.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make(map[string][]string, len(sessions))
+	for _, session := range sessions {
+		peers[session.PublicKey] = []string{session.Address}
+	}
+	return peers, nil
+}
+
+// Run tears down sessions whose stream has ended until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.SessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.checkSessions()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSessions ends every session that is no longer paid for
+func (m *Manager) checkSessions() {
+	sessions, err := m.Sessions()
+	if err != nil {
+		m.logger.Errorf("Failed to load sessions: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		reason, err := m.endReason(&session)
+		if err != nil {
+			m.logger.Warnf("Failed to check session of peer %s: %v", session.PublicKey, err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		m.mu.Lock()
+		if err := m.end(&session, reason); err != nil {
+			m.logger.Errorf("Failed to end session of peer %s: %v", session.PublicKey, err)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// endReason returns why a session should end, or "" if it is still paid for
+func (m *Manager) endReason(session *types.Session) (string, error) {
+	if _, exists := m.wireguard.GetPeer(session.PublicKey); !exists {
+		// Removed through the peers API or by billing
+		return ReasonPeerRemoved, nil
+	}
+
+	if session.EndsAt > 0 && time.Now().Unix() >= session.EndsAt {
+		return ReasonStreamEnded, nil
+	}
+
+	stream, err := m.blockchain.GetStream(session.StreamID)
+	if errors.Is(err, blockchain.ErrStreamNotFound) {
+		return ReasonStreamInactive, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !stream.IsActive {
+		return ReasonStreamInactive, nil
+	}
+	return "", nil
+}
+
+// end disconnects a peer and deletes its session. Callers must hold m.mu.
+func (m *Manager) end(session *types.Session, reason string) error {
+	if _, exists := m.wireguard.GetPeer(session.PublicKey); exists {
+		if err := m.wireguard.RemovePeer(session.PublicKey); err != nil {
+			return err
+		}
+	}
+
+	if err := m.billing.Detach(session.PublicKey); err != nil && !errors.Is(err, billing.ErrAccountNotFound) {
+		m.logger.Warnf("Failed to stop billing peer %s: %v", session.PublicKey, err)
+	}
+
+	if err := m.store.Delete(sessionsBucket, session.PublicKey); err != nil {
+		return err
+	}
+
+	m.logger.Infof("Session of peer %s ended: %s", session.PublicKey, reason)
+	m.notify(types.WebSocketMessage{
+		Type: "session_ended",
+		Payload: map[string]interface{}{
+			"publicKey": session.PublicKey,
+			"streamId":  session.StreamID,
+			"reason":    reason,
+		},
+	})
+	return nil
+}
+
+// checkStream verifies that stream is an active, funded stream from payer to
+// the node wallet
+func (m *Manager) checkStream(stream *types.PaymentStream, payer string) error {
+	if !strings.EqualFold(stream.Recipient, m.blockchain.GetWalletAddress()) {
+		return fmt.Errorf("%w: stream %s does not pay this node", ErrInvalidSession, stream.StreamID)
+	}
+	if !strings.EqualFold(stream.Sender, payer) {
+		return fmt.Errorf("%w: stream %s is not from %s", ErrInvalidSession, stream.StreamID, payer)
+	}
+	if !stream.IsActive {
+		return fmt.Errorf("%w: stream %s is not active", ErrInvalidSession, stream.StreamID)
+	}
+	if time.Now().Unix() >= int64(stream.EndTime) {
+		return fmt.Errorf("%w: stream %s has ended", ErrInvalidSession, stream.StreamID)
+	}
+
+	amount, _ := new(big.Int).SetString(stream.Amount, 10)
+	withdrawn, _ := new(big.Int).SetString(stream.Withdrawn, 10)
+	if amount == nil || withdrawn == nil {
+		return fmt.Errorf("%w: stream %s has an invalid balance", ErrInvalidSession, stream.StreamID)
+	}
+	if remaining := new(big.Int).Sub(amount, withdrawn); remaining.Sign() <= 0 || remaining.Cmp(m.minAmount) < 0 {
+		return fmt.Errorf("%w: stream %s is not funded", ErrInvalidSession, stream.StreamID)
+	}
+	return nil
+}
+
+// RequestMessage returns the message a payer signs to open a session for the
+// WireGuard key publicKey, paid by streamID to the node at address
+func RequestMessage(publicKey, streamID, node string, expiry int64) []byte {
+	return sessionMessage("dVPN session request", publicKey, streamID, node, expiry)
+}
+
+// CloseMessage returns the message a payer signs to close the session of the
+// WireGuard key publicKey, paid by streamID to the node at address
+func CloseMessage(publicKey, streamID, node string, expiry int64) []byte {
+	return sessionMessage("dVPN session close", publicKey, streamID, node, expiry)
+}
+
+// sessionMessage formats a signed session message. The title keeps a
+// signature for one action from being used for another.
+func sessionMessage(title, publicKey, streamID, node string, expiry int64) []byte {
+	return []byte(fmt.Sprintf("%s\nPublic key: %s\nStream: %s\nNode: %s\nExpires: %d",
+		title, publicKey, strings.ToLower(streamID), common.HexToAddress(node).Hex(), expiry))
+}
+
+// verifyRequest checks that signature is an unexpired EIP-191 signature of
+// message by sender
+func verifyRequest(message []byte, signature string, sender string, expiry int64, now time.Time) error {
+	if expiry <= now.Unix() {
+		return fmt.Errorf("%w: request expired", ErrInvalidSignature)
+	}
+	if expiry > now.Add(maxRequestLifetime).Unix() {
+		return fmt.Errorf("%w: expiry is more than %s away", ErrInvalidSignature, maxRequestLifetime)
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: signature must be 65 bytes of hex", ErrInvalidSignature)
+	}
+	// Wallets return V as 27/28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash(message), sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*publicKey); !strings.EqualFold(signer.Hex(), sender) {
+		return fmt.Errorf("%w: signed by %s, not the stream sender %s", ErrInvalidSignature, signer.Hex(), sender)
+	}
+	return nil
+}
+
+// removePeer rolls back a peer added for a session that could not be created
+func (m *Manager) removePeer(publicKey string) {
+	if err := m.wireguard.RemovePeer(publicKey); err != nil {
+		m.logger.Errorf("Failed to remove peer %s: %v", publicKey, err)
+	}
+}
+
+// session returns the stored session of a peer. Callers must hold m.mu.
+func (m *Manager) session(publicKey string) (*types.Session, error) {
+	var session types.Session
+	found, err := m.store.Get(sessionsBucket, publicKey, &session)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, publicKey)
+	}
+	return &session, nil
+}
+
+// sessions returns every stored session. Callers must hold m.mu.
+func (m *Manager) sessions() ([]types.Session, error) {
+	var sessions []types.Session
+	err := m.store.ForEach(sessionsBucket, func(_ string, value []byte) error {
+		var session types.Session
+		if err := json.Unmarshal(value, &session); err != nil {
+			return err
+		}
+		sessions = append(sessions, session)
+		return nil
+	})
+	return sessions, err
+}
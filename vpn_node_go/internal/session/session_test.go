@@ -0,0 +1,136 @@
+package session
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	testPeer   = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	testStream = "0x00000000000000000000000000000000000000000000000000000000000000aa"
+	testNode   = "0x00000000000000000000000000000000000000bb"
+)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+// sign returns the wallet-style EIP-191 signature of message, with V as 27/28
+func sign(t *testing.T, key *ecdsa.PrivateKey, message []byte) string {
+	t.Helper()
+	signature, err := crypto.Sign(accounts.TextHash(message), key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature)
+}
+
+func TestVerifyRequest(t *testing.T) {
+	payer := newTestKey(t)
+	sender := crypto.PubkeyToAddress(payer.PublicKey).Hex()
+	now := time.Unix(1_700_000_000, 0)
+	expiry := now.Add(10 * time.Minute).Unix()
+	message := RequestMessage(testPeer, testStream, testNode, expiry)
+
+	if err := verifyRequest(message, sign(t, payer, message), sender, expiry, now); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+
+	// V as 0/1 is accepted as well
+	raw, _ := crypto.Sign(accounts.TextHash(message), payer)
+	if err := verifyRequest(message, hexutil.Encode(raw), sender, expiry, now); err != nil {
+		t.Fatalf("valid request with V 0/1 rejected: %v", err)
+	}
+}
+
+func TestVerifyRequestRejects(t *testing.T) {
+	payer := newTestKey(t)
+	sender := crypto.PubkeyToAddress(payer.PublicKey).Hex()
+	now := time.Unix(1_700_000_000, 0)
+	expiry := now.Add(10 * time.Minute).Unix()
+	message := RequestMessage(testPeer, testStream, testNode, expiry)
+
+	otherPeer := "HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw="
+	tests := []struct {
+		name      string
+		signature string
+		expiry    int64
+	}{
+		{"unsigned", "", expiry},
+		{"not hex", "signed", expiry},
+		{"truncated", sign(t, payer, message)[:100], expiry},
+		{"signed by someone else", sign(t, newTestKey(t), message), expiry},
+		{"signed for another key", sign(t, payer, RequestMessage(otherPeer, testStream, testNode, expiry)), expiry},
+		{"signed for another stream", sign(t, payer, RequestMessage(testPeer, "0x01", testNode, expiry)), expiry},
+		{"signed for another node", sign(t, payer, RequestMessage(testPeer, testStream, "0x01", expiry)), expiry},
+		{"expiry changed", sign(t, payer, message), expiry + 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := RequestMessage(testPeer, testStream, testNode, tt.expiry)
+			if err := verifyRequest(message, tt.signature, sender, tt.expiry, now); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("verifyRequest = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestExpiry(t *testing.T) {
+	payer := newTestKey(t)
+	sender := crypto.PubkeyToAddress(payer.PublicKey).Hex()
+	now := time.Unix(1_700_000_000, 0)
+
+	for name, expiry := range map[string]int64{
+		"expired":       now.Add(-time.Second).Unix(),
+		"expiring now":  now.Unix(),
+		"too far ahead": now.Add(maxRequestLifetime + time.Minute).Unix(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			message := RequestMessage(testPeer, testStream, testNode, expiry)
+			if err := verifyRequest(message, sign(t, payer, message), sender, expiry, now); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("verifyRequest = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestRequestMessageIsCanonical(t *testing.T) {
+	lower := RequestMessage(testPeer, testStream, "0x00000000000000000000000000000000000000bb", 1)
+	mixed := RequestMessage(testPeer, "0x00000000000000000000000000000000000000000000000000000000000000AA", "0x00000000000000000000000000000000000000BB", 1)
+	if string(lower) != string(mixed) {
+		t.Fatalf("message depends on hex case:\n%s\n%s", lower, mixed)
+	}
+}
+
+func TestCloseMessageIsNotARequest(t *testing.T) {
+	payer := newTestKey(t)
+	sender := crypto.PubkeyToAddress(payer.PublicKey).Hex()
+	now := time.Unix(1_700_000_000, 0)
+	expiry := now.Add(10 * time.Minute).Unix()
+
+	closeSig := sign(t, payer, CloseMessage(testPeer, testStream, testNode, expiry))
+	if err := verifyRequest(CloseMessage(testPeer, testStream, testNode, expiry), closeSig, sender, expiry, now); err != nil {
+		t.Fatalf("verifyRequest(close) = %v, want nil", err)
+	}
+	if err := verifyRequest(RequestMessage(testPeer, testStream, testNode, expiry), closeSig, sender, expiry, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("close signature accepted as a session request: %v", err)
+	}
+
+	requestSig := sign(t, payer, RequestMessage(testPeer, testStream, testNode, expiry))
+	if err := verifyRequest(CloseMessage(testPeer, testStream, testNode, expiry), requestSig, sender, expiry, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("session request signature accepted as a close: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package shaping
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Rule is the rate limit of one peer on the WireGuard interface. Rates are
+// in bytes per second; 0 leaves that direction unshaped.
+type Rule struct {
+	ID        uint16   // tc class minor, unique per peer
+	Addresses []net.IP // tunnel addresses of the peer
+	Up        uint64   // peer to node, policed on ingress
+	Down      uint64   // node to peer, shaped by an HTB class on egress
+}
+
+// Backend installs rate limit rules on an interface
+type Backend interface {
+	// Setup replaces the qdiscs of iface with an empty shaping tree
+	Setup(iface string) error
+
+	// SetPeer installs or replaces the rule of a peer
+	SetPeer(iface string, rule Rule) error
+
+	// DeletePeer removes the rule with id
+	DeletePeer(iface string, id uint16) error
+}
+
+// FakeBackend keeps rules in memory instead of the kernel, for tests and
+// platforms without traffic control
+type FakeBackend struct {
+	mu    sync.Mutex
+	rules map[string]map[uint16]Rule
+}
+
+// NewFakeBackend creates an empty in-memory backend
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{rules: make(map[string]map[uint16]Rule)}
+}
+
+// Setup clears the rules of iface
+func (f *FakeBackend) Setup(iface string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rules[iface] = make(map[uint16]Rule)
+	return nil
+}
+
+// SetPeer stores rule
+func (f *FakeBackend) SetPeer(iface string, rule Rule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules, exists := f.rules[iface]
+	if !exists {
+		return fmt.Errorf("interface %s is not set up", iface)
+	}
+	rules[rule.ID] = rule
+	return nil
+}
+
+// DeletePeer removes the rule with id
+func (f *FakeBackend) DeletePeer(iface string, id uint16) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules, exists := f.rules[iface]
+	if !exists {
+		return fmt.Errorf("interface %s is not set up", iface)
+	}
+	delete(rules, id)
+	return nil
+}
+
+// Rules returns the rules of iface ordered by ID
+func (f *FakeBackend) Rules(iface string) []Rule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules := make([]Rule, 0, len(f.rules[iface]))
+	for _, rule := range f.rules[iface] {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
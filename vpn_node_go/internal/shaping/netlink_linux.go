@@ -0,0 +1,223 @@
+//go:build linux
+
+package shaping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// WireGuard interfaces carry bare IP packets, so header offsets start at the
+// IPv4 header
+const (
+	srcAddressOffset = 12
+	dstAddressOffset = 16
+
+	// minBurst lets a policed peer send a few full-size packets back to back
+	minBurst = 16 * 1500
+)
+
+var (
+	rootHandle    = netlink.MakeHandle(1, 0)
+	ingressHandle = netlink.MakeHandle(0xffff, 0)
+)
+
+// NetlinkBackend shapes traffic with kernel traffic control. Download is
+// shaped by an HTB class per peer under a root HTB qdisc, matched by a u32
+// filter on the destination address. Upload is policed on the ingress qdisc
+// by a u32 filter on the source address, since ingress traffic cannot be
+// queued. Traffic of unknown addresses is left unshaped.
+type NetlinkBackend struct{}
+
+// NewNetlinkBackend creates a traffic control backend
+func NewNetlinkBackend() (Backend, error) {
+	return &NetlinkBackend{}, nil
+}
+
+// Setup replaces the root and ingress qdiscs of iface
+func (b *NetlinkBackend) Setup(iface string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("failed to list qdiscs: %w", err)
+	}
+	for _, qdisc := range qdiscs {
+		parent := qdisc.Attrs().Parent
+		if parent != netlink.HANDLE_ROOT && parent != netlink.HANDLE_INGRESS {
+			continue
+		}
+		if err := netlink.QdiscDel(qdisc); err != nil {
+			return fmt.Errorf("failed to delete qdisc %s: %w", qdisc.Type(), err)
+		}
+	}
+
+	// No default class: unclassified traffic bypasses shaping
+	root := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    rootHandle,
+		Parent:    netlink.HANDLE_ROOT,
+	})
+	if err := netlink.QdiscAdd(root); err != nil {
+		return fmt.Errorf("failed to add htb qdisc: %w", err)
+	}
+
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    ingressHandle,
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	if err := netlink.QdiscAdd(ingress); err != nil {
+		return fmt.Errorf("failed to add ingress qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// SetPeer replaces the class and filters of rule.ID
+func (b *NetlinkBackend) SetPeer(iface string, rule Rule) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+
+	if err := b.deletePeer(link, rule.ID); err != nil {
+		return err
+	}
+
+	if rule.Down > 0 {
+		classID := netlink.MakeHandle(1, rule.ID)
+		class := netlink.NewHtbClass(netlink.ClassAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    rootHandle,
+			Handle:    classID,
+		}, netlink.HtbClassAttrs{
+			Rate: rule.Down * 8,
+			Ceil: rule.Down * 8,
+		})
+		if err := netlink.ClassReplace(class); err != nil {
+			return fmt.Errorf("failed to set class %x: %w", rule.ID, err)
+		}
+
+		for _, ip := range rule.Addresses {
+			filter, err := addressFilter(link, rootHandle, classID, ip, dstAddressOffset)
+			if err != nil {
+				return err
+			}
+			if err := netlink.FilterAdd(filter); err != nil {
+				return fmt.Errorf("failed to add egress filter for %s: %w", ip, err)
+			}
+		}
+	}
+
+	if rule.Up > 0 {
+		rate := rule.Up
+		if rate > math.MaxUint32 {
+			rate = math.MaxUint32
+		}
+		burst := rate / 10
+		if burst < minBurst {
+			burst = minBurst
+		}
+
+		for _, ip := range rule.Addresses {
+			// The class ID of an ingress filter only tags it as the peer's
+			filter, err := addressFilter(link, ingressHandle, netlink.MakeHandle(0xffff, rule.ID), ip, srcAddressOffset)
+			if err != nil {
+				return err
+			}
+
+			police := netlink.NewPoliceAction()
+			police.Rate = uint32(rate)
+			police.Burst = uint32(burst)
+			police.ExceedAction = netlink.TC_POLICE_SHOT
+			filter.Actions = []netlink.Action{police}
+
+			if err := netlink.FilterAdd(filter); err != nil {
+				return fmt.Errorf("failed to add ingress filter for %s: %w", ip, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeletePeer removes the class and filters of id
+func (b *NetlinkBackend) DeletePeer(iface string, id uint16) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+	return b.deletePeer(link, id)
+}
+
+// deletePeer removes the filters tagged with id, then its class
+func (b *NetlinkBackend) deletePeer(link netlink.Link, id uint16) error {
+	for _, parent := range []uint32{rootHandle, ingressHandle} {
+		major, _ := netlink.MajorMinor(parent)
+		classID := netlink.MakeHandle(major, id)
+
+		filters, err := netlink.FilterList(link, parent)
+		if err != nil {
+			return fmt.Errorf("failed to list filters: %w", err)
+		}
+		for _, filter := range filters {
+			u32, ok := filter.(*netlink.U32)
+			if !ok || u32.ClassId != classID {
+				continue
+			}
+			if err := netlink.FilterDel(u32); err != nil {
+				return fmt.Errorf("failed to delete filter of %x: %w", id, err)
+			}
+		}
+	}
+
+	class := netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: link.Attrs().Index,
+		Parent:    rootHandle,
+		Handle:    netlink.MakeHandle(1, id),
+	}, netlink.HtbClassAttrs{})
+	if err := netlink.ClassDel(class); err != nil && !errors.Is(err, unix.ENOENT) {
+		return fmt.Errorf("failed to delete class %x: %w", id, err)
+	}
+
+	return nil
+}
+
+// addressFilter builds a u32 filter matching the IPv4 address at offset
+func addressFilter(link netlink.Link, parent, classID uint32, ip net.IP, offset int32) (*netlink.U32, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("only IPv4 addresses can be shaped: %s", ip)
+	}
+
+	return &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Priority:  1,
+			Protocol:  unix.ETH_P_IP,
+		},
+		ClassId: classID,
+		Sel: &netlink.TcU32Sel{
+			Flags: netlink.TC_U32_TERMINAL,
+			Keys: []netlink.TcU32Key{
+				{
+					Mask: 0xffffffff,
+					Val:  uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3]),
+					Off:  offset,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package shaping
+
+import "errors"
+
+// NewNetlinkBackend fails on platforms without Linux traffic control
+func NewNetlinkBackend() (Backend, error) {
+	return nil, errors.New("traffic shaping requires Linux")
+}
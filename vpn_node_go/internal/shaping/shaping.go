@@ -0,0 +1,457 @@
+package shaping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const shapingBucket = "shaping"
+
+// Backends selectable with SHAPING_BACKEND
+const (
+	BackendNetlink = "netlink"
+	BackendFake    = "fake"
+)
+
+var (
+	// ErrInvalidPeer is returned for a malformed peer public key
+	ErrInvalidPeer = errors.New("invalid peer public key")
+
+	// ErrUnknownTier is returned when a peer is moved to a tier that is not configured
+	ErrUnknownTier = errors.New("unknown shaping tier")
+
+	// ErrPeerNotFound is returned for a peer that is neither shaped nor assigned a tier
+	ErrPeerNotFound = errors.New("peer has no shaping rule")
+
+	// ErrNoClassIDs is returned when every tc class ID is taken
+	ErrNoClassIDs = errors.New("no free traffic control class IDs")
+)
+
+// assignment is the persisted tier or custom rates of a peer
+type assignment struct {
+	Tier   string `json:"tier,omitempty"`
+	Custom bool   `json:"custom,omitempty"`
+	Down   uint64 `json:"down,omitempty"`
+	Up     uint64 `json:"up,omitempty"`
+}
+
+// peerRule is the rule of a peer that is on the device
+type peerRule struct {
+	id        uint16
+	addresses []string
+	ips       []net.IP
+}
+
+// Shaper caps the bandwidth of each peer on the WireGuard interface. A peer
+// gets the rates of its tier, custom rates, or the node defaults, lowered by
+// the throttles billing and quotas put on it. Tier assignments are persisted so
+// they survive reconnects and restarts; the kernel rules themselves are
+// rebuilt as peers are applied.
+type Shaper struct {
+	config  *types.NodeConfig
+	backend Backend
+	store   *store.Store
+	logger  *logrus.Logger
+	tiers   map[string]types.ShapingTier
+
+	mu          sync.Mutex
+	assignments map[string]assignment
+	throttles   map[string]map[string]uint64 // by peer, then by source
+	rules       map[string]*peerRule
+	ids         map[uint16]string
+	nextID      uint16
+}
+
+// NewBackend creates the backend named by SHAPING_BACKEND
+func NewBackend(config *types.NodeConfig) (Backend, error) {
+	switch config.ShapingBackend {
+	case BackendNetlink, "":
+		return NewNetlinkBackend()
+	case BackendFake:
+		return NewFakeBackend(), nil
+	}
+	return nil, fmt.Errorf("invalid SHAPING_BACKEND: %s", config.ShapingBackend)
+}
+
+// NewShaper parses the configured tiers and installs an empty shaping tree on
+// the WireGuard interface
+func NewShaper(config *types.NodeConfig, backend Backend, db *store.Store, logger *logrus.Logger) (*Shaper, error) {
+	tiers, err := parseTiers(config.ShapingTiers)
+	if err != nil {
+		return nil, err
+	}
+	if config.ShapingDefaultTier != "" {
+		if _, exists := tiers[config.ShapingDefaultTier]; !exists {
+			return nil, fmt.Errorf("invalid SHAPING_DEFAULT_TIER: %s is not in SHAPING_TIERS", config.ShapingDefaultTier)
+		}
+	}
+
+	s := &Shaper{
+		config:      config,
+		backend:     backend,
+		store:       db,
+		logger:      logger,
+		tiers:       tiers,
+		assignments: make(map[string]assignment),
+		throttles:   make(map[string]map[string]uint64),
+		rules:       make(map[string]*peerRule),
+		ids:         make(map[uint16]string),
+		nextID:      1,
+	}
+
+	err = db.ForEach(shapingBucket, func(key string, value []byte) error {
+		var a assignment
+		if err := json.Unmarshal(value, &a); err != nil {
+			return err
+		}
+		if a.Tier != "" {
+			if _, exists := tiers[a.Tier]; !exists {
+				logger.Warnf("Peer %s was on removed shaping tier %s, using the default", key, a.Tier)
+				a.Tier = ""
+			}
+		}
+		s.assignments[key] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shaping assignments: %w", err)
+	}
+
+	if err := backend.Setup(config.WGInterface); err != nil {
+		return nil, fmt.Errorf("failed to set up traffic shaping on %s: %w", config.WGInterface, err)
+	}
+
+	return s, nil
+}
+
+// Tiers returns the configured tiers ordered by name
+func (s *Shaper) Tiers() []types.ShapingTier {
+	tiers := make([]types.ShapingTier, 0, len(s.tiers))
+	for _, tier := range s.tiers {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Name < tiers[j].Name })
+	return tiers
+}
+
+// Apply installs or refreshes the rule of a peer routed to addresses
+func (s *Shaper) Apply(publicKey string, addresses []string) error {
+	ips := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		ip, err := parseAddress(address)
+		if err != nil {
+			return err
+		}
+		ips = append(ips, ip)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, exists := s.rules[publicKey]
+	if !exists {
+		id, err := s.allocateID()
+		if err != nil {
+			return err
+		}
+		rule = &peerRule{id: id}
+	}
+	rule.addresses = addresses
+	rule.ips = ips
+
+	if err := s.install(publicKey, rule); err != nil {
+		return err
+	}
+
+	s.rules[publicKey] = rule
+	s.ids[rule.id] = publicKey
+	return nil
+}
+
+// Remove deletes the rule of a peer. Its tier assignment is kept for when it
+// reconnects.
+func (s *Shaper) Remove(publicKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.throttles, publicKey)
+
+	rule, exists := s.rules[publicKey]
+	if !exists {
+		return nil
+	}
+	if err := s.backend.DeletePeer(s.config.WGInterface, rule.id); err != nil {
+		return fmt.Errorf("failed to remove shaping rule: %w", err)
+	}
+
+	delete(s.rules, publicKey)
+	delete(s.ids, rule.id)
+	return nil
+}
+
+// SetTier moves a peer to tier, or back to the default when tier is empty
+func (s *Shaper) SetTier(publicKey, tier string) (*types.PeerShaping, error) {
+	if tier != "" {
+		if _, exists := s.tiers[tier]; !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTier, tier)
+		}
+	}
+	return s.assign(publicKey, assignment{Tier: tier})
+}
+
+// SetRates gives a peer custom rate ceilings in bytes per second, 0 being unlimited
+func (s *Shaper) SetRates(publicKey string, down, up uint64) (*types.PeerShaping, error) {
+	return s.assign(publicKey, assignment{Custom: true, Down: down, Up: up})
+}
+
+// Throttle temporarily caps both directions of a peer at bytesPerSecond on
+// behalf of source. Throttles from different sources are kept apart and the
+// lowest one applies.
+func (s *Shaper) Throttle(publicKey, source string, bytesPerSecond uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.throttles[publicKey] == nil {
+		s.throttles[publicKey] = make(map[string]uint64)
+	}
+	s.throttles[publicKey][source] = bytesPerSecond
+	return s.refresh(publicKey)
+}
+
+// Unthrottle lifts the throttle source put on a peer, leaving those of other
+// sources in place
+func (s *Shaper) Unthrottle(publicKey, source string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.throttles[publicKey][source]; !exists {
+		return nil
+	}
+	delete(s.throttles[publicKey], source)
+	if len(s.throttles[publicKey]) == 0 {
+		delete(s.throttles, publicKey)
+	}
+	return s.refresh(publicKey)
+}
+
+// Peer returns the rate limit of a peer
+func (s *Shaper) Peer(publicKey string) (*types.PeerShaping, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, shaped := s.rules[publicKey]
+	_, assigned := s.assignments[publicKey]
+	if !shaped && !assigned {
+		return nil, ErrPeerNotFound
+	}
+	return s.view(publicKey), nil
+}
+
+// Peers returns the rate limits of shaped and assigned peers
+func (s *Shaper) Peers() []*types.PeerShaping {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make(map[string]bool, len(s.rules)+len(s.assignments))
+	for publicKey := range s.rules {
+		keys[publicKey] = true
+	}
+	for publicKey := range s.assignments {
+		keys[publicKey] = true
+	}
+
+	peers := make([]*types.PeerShaping, 0, len(keys))
+	for publicKey := range keys {
+		peers = append(peers, s.view(publicKey))
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].PublicKey < peers[j].PublicKey })
+	return peers
+}
+
+// assign persists the assignment of a peer and re-applies its rule
+func (s *Shaper) assign(publicKey string, a assignment) (*types.PeerShaping, error) {
+	if _, err := wgtypes.ParseKey(publicKey); err != nil {
+		return nil, ErrInvalidPeer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a == (assignment{}) {
+		if err := s.store.Delete(shapingBucket, publicKey); err != nil {
+			return nil, fmt.Errorf("failed to delete shaping assignment: %w", err)
+		}
+		delete(s.assignments, publicKey)
+	} else {
+		if err := s.store.Put(shapingBucket, publicKey, a); err != nil {
+			return nil, fmt.Errorf("failed to save shaping assignment: %w", err)
+		}
+		s.assignments[publicKey] = a
+	}
+
+	if err := s.refresh(publicKey); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Shaping of peer %s changed to %s", publicKey, describe(s.view(publicKey)))
+	return s.view(publicKey), nil
+}
+
+// refresh re-installs the rule of a peer if it is on the device
+func (s *Shaper) refresh(publicKey string) error {
+	rule, exists := s.rules[publicKey]
+	if !exists {
+		return nil
+	}
+	return s.install(publicKey, rule)
+}
+
+// install pushes the effective rates of a peer to the backend
+func (s *Shaper) install(publicKey string, rule *peerRule) error {
+	down, up := s.rates(publicKey)
+	err := s.backend.SetPeer(s.config.WGInterface, Rule{
+		ID:        rule.id,
+		Addresses: rule.ips,
+		Up:        up,
+		Down:      down,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply shaping rule: %w", err)
+	}
+	return nil
+}
+
+// rates returns the effective ceilings of a peer
+func (s *Shaper) rates(publicKey string) (down, up uint64) {
+	a := s.assignments[publicKey]
+	switch {
+	case a.Custom:
+		down, up = a.Down, a.Up
+	case a.Tier != "":
+		down, up = s.tiers[a.Tier].Down, s.tiers[a.Tier].Up
+	case s.config.ShapingDefaultTier != "":
+		tier := s.tiers[s.config.ShapingDefaultTier]
+		down, up = tier.Down, tier.Up
+	default:
+		down, up = s.config.ShapingDefaultDown, s.config.ShapingDefaultUp
+	}
+
+	for _, throttle := range s.throttles[publicKey] {
+		if throttle > 0 {
+			down, up = lowest(down, throttle), lowest(up, throttle)
+		}
+	}
+	return down, up
+}
+
+// view describes the rate limit of a peer
+func (s *Shaper) view(publicKey string) *types.PeerShaping {
+	peer := &types.PeerShaping{PublicKey: publicKey}
+	peer.Down, peer.Up = s.rates(publicKey)
+
+	if throttles := s.throttles[publicKey]; len(throttles) > 0 {
+		peer.Throttles = make(map[string]uint64, len(throttles))
+		for source, throttle := range throttles {
+			peer.Throttles[source] = throttle
+		}
+	}
+
+	if rule, exists := s.rules[publicKey]; exists {
+		peer.ClassID = rule.id
+		peer.Addresses = rule.addresses
+	}
+
+	a := s.assignments[publicKey]
+	switch {
+	case a.Custom:
+	case a.Tier != "":
+		peer.Tier = a.Tier
+	default:
+		peer.Tier = s.config.ShapingDefaultTier
+	}
+	return peer
+}
+
+// allocateID returns a free tc class minor
+func (s *Shaper) allocateID() (uint16, error) {
+	// Minor 0 is the qdisc itself and 0xffff is reserved
+	for range 0xfffe {
+		id := s.nextID
+		s.nextID++
+		if s.nextID == 0xffff {
+			s.nextID = 1
+		}
+		if _, taken := s.ids[id]; !taken {
+			return id, nil
+		}
+	}
+	return 0, ErrNoClassIDs
+}
+
+// parseTiers parses SHAPING_TIERS entries of the form name:down:up
+func parseTiers(entries []string) (map[string]types.ShapingTier, error) {
+	tiers := make(map[string]types.ShapingTier, len(entries))
+	for _, entry := range entries {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid SHAPING_TIERS entry %q: expected name:down:up", entry)
+		}
+
+		down, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid down rate in SHAPING_TIERS entry %q: %w", entry, err)
+		}
+		up, err := strconv.ParseUint(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid up rate in SHAPING_TIERS entry %q: %w", entry, err)
+		}
+
+		if _, exists := tiers[parts[0]]; exists {
+			return nil, fmt.Errorf("duplicate SHAPING_TIERS entry %q", parts[0])
+		}
+		tiers[parts[0]] = types.ShapingTier{Name: parts[0], Down: down, Up: up}
+	}
+	return tiers, nil
+}
+
+// parseAddress parses a peer address given as an IP or a CIDR
+func parseAddress(address string) (net.IP, error) {
+	if ip, _, err := net.ParseCIDR(address); err == nil {
+		return ip, nil
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("invalid peer address: %s", address)
+}
+
+// lowest returns the lower of two rates, where 0 is unlimited
+func lowest(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// describe formats the rates of a peer for logs
+func describe(peer *types.PeerShaping) string {
+	rate := func(bytesPerSecond uint64) string {
+		if bytesPerSecond == 0 {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%d B/s", bytesPerSecond)
+	}
+	return fmt.Sprintf("down %s, up %s", rate(peer.Down), rate(peer.Up))
+}
@@ -0,0 +1,270 @@
+package shaping
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const testInterface = "wg-test"
+
+func newTestConfig() *types.NodeConfig {
+	return &types.NodeConfig{
+		WGInterface:        testInterface,
+		ShapingTiers:       []string{"basic:1000:500", "pro:8000:4000"},
+		ShapingDefaultDown: 2000,
+		ShapingDefaultUp:   1000,
+	}
+}
+
+func newTestShaper(t *testing.T, config *types.NodeConfig, backend Backend, path string) *Shaper {
+	t.Helper()
+	db, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	shaper, err := NewShaper(config, backend, db, logger)
+	if err != nil {
+		t.Fatalf("NewShaper: %v", err)
+	}
+	return shaper
+}
+
+func newTestPeer(t *testing.T) string {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key.PublicKey().String()
+}
+
+// onlyRule returns the single rule installed on the test interface
+func onlyRule(t *testing.T, backend *FakeBackend) Rule {
+	t.Helper()
+	rules := backend.Rules(testInterface)
+	if len(rules) != 1 {
+		t.Fatalf("backend has %d rules, want 1: %+v", len(rules), rules)
+	}
+	return rules[0]
+}
+
+func TestApplyAndRemove(t *testing.T) {
+	backend := NewFakeBackend()
+	shaper := newTestShaper(t, newTestConfig(), backend, filepath.Join(t.TempDir(), "node.db"))
+	peerA, peerB := newTestPeer(t), newTestPeer(t)
+
+	if err := shaper.Apply(peerA, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	rule := onlyRule(t, backend)
+	if rule.Down != 2000 || rule.Up != 1000 {
+		t.Fatalf("rule rates = %d/%d, want the node defaults 2000/1000", rule.Down, rule.Up)
+	}
+	if len(rule.Addresses) != 1 || rule.Addresses[0].String() != "10.8.0.2" {
+		t.Fatalf("rule addresses = %v, want [10.8.0.2]", rule.Addresses)
+	}
+
+	// Re-applying with new addresses keeps the class
+	if err := shaper.Apply(peerA, []string{"10.8.0.3/32"}); err != nil {
+		t.Fatalf("Apply again: %v", err)
+	}
+	if again := onlyRule(t, backend); again.ID != rule.ID || again.Addresses[0].String() != "10.8.0.3" {
+		t.Fatalf("re-applied rule = %+v, want class %d on 10.8.0.3", again, rule.ID)
+	}
+
+	if err := shaper.Apply(peerB, []string{"10.8.0.4/32"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	rules := backend.Rules(testInterface)
+	if len(rules) != 2 || rules[0].ID == rules[1].ID {
+		t.Fatalf("rules = %+v, want two distinct classes", rules)
+	}
+
+	if err := shaper.Remove(peerA); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if remaining := onlyRule(t, backend); remaining.Addresses[0].String() != "10.8.0.4" {
+		t.Fatalf("remaining rule = %+v, want peer B's", remaining)
+	}
+	if _, err := shaper.Peer(peerA); !errors.Is(err, ErrPeerNotFound) {
+		t.Fatalf("Peer after Remove = %v, want ErrPeerNotFound", err)
+	}
+	if err := shaper.Remove(peerA); err != nil {
+		t.Fatalf("Remove of a removed peer: %v", err)
+	}
+
+	if err := shaper.Apply(peerA, []string{"not-an-address"}); err == nil {
+		t.Fatal("Apply accepted an invalid address")
+	}
+}
+
+func TestSetTierAndRates(t *testing.T) {
+	backend := NewFakeBackend()
+	shaper := newTestShaper(t, newTestConfig(), backend, filepath.Join(t.TempDir(), "node.db"))
+	peer := newTestPeer(t)
+
+	if err := shaper.Apply(peer, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	view, err := shaper.SetTier(peer, "pro")
+	if err != nil {
+		t.Fatalf("SetTier: %v", err)
+	}
+	if view.Tier != "pro" || view.Down != 8000 || view.Up != 4000 {
+		t.Fatalf("view = %+v, want tier pro at 8000/4000", view)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 8000 || rule.Up != 4000 {
+		t.Fatalf("rule rates = %d/%d, want 8000/4000", rule.Down, rule.Up)
+	}
+
+	if _, err := shaper.SetRates(peer, 300, 0); err != nil {
+		t.Fatalf("SetRates: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 300 || rule.Up != 0 {
+		t.Fatalf("rule rates = %d/%d, want custom 300/unlimited", rule.Down, rule.Up)
+	}
+
+	if _, err := shaper.SetTier(peer, "gold"); !errors.Is(err, ErrUnknownTier) {
+		t.Fatalf("SetTier to an unknown tier = %v, want ErrUnknownTier", err)
+	}
+	if _, err := shaper.SetTier("not-a-key", "pro"); !errors.Is(err, ErrInvalidPeer) {
+		t.Fatalf("SetTier for a bad key = %v, want ErrInvalidPeer", err)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	backend := NewFakeBackend()
+	shaper := newTestShaper(t, newTestConfig(), backend, filepath.Join(t.TempDir(), "node.db"))
+	peer := newTestPeer(t)
+
+	if err := shaper.Apply(peer, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if err := shaper.Throttle(peer, "billing", 500); err != nil {
+		t.Fatalf("Throttle: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 500 || rule.Up != 500 {
+		t.Fatalf("throttled rates = %d/%d, want 500/500", rule.Down, rule.Up)
+	}
+
+	// A throttle above a ceiling does not raise it
+	if err := shaper.Throttle(peer, "quota:daily", 1500); err != nil {
+		t.Fatalf("Throttle: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 500 || rule.Up != 500 {
+		t.Fatalf("rates with two throttles = %d/%d, want the lowest 500/500", rule.Down, rule.Up)
+	}
+
+	view, err := shaper.Peer(peer)
+	if err != nil {
+		t.Fatalf("Peer: %v", err)
+	}
+	if len(view.Throttles) != 2 || view.Throttles["billing"] != 500 || view.Throttles["quota:daily"] != 1500 {
+		t.Fatalf("throttles = %v, want billing and quota:daily", view.Throttles)
+	}
+
+	// Lifting one source leaves the other in place
+	if err := shaper.Unthrottle(peer, "billing"); err != nil {
+		t.Fatalf("Unthrottle: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 1500 || rule.Up != 1000 {
+		t.Fatalf("rates after lifting billing = %d/%d, want 1500/1000", rule.Down, rule.Up)
+	}
+	if err := shaper.Unthrottle(peer, "billing"); err != nil {
+		t.Fatalf("Unthrottle of a lifted source: %v", err)
+	}
+
+	if err := shaper.Unthrottle(peer, "quota:daily"); err != nil {
+		t.Fatalf("Unthrottle: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 2000 || rule.Up != 1000 {
+		t.Fatalf("unthrottled rates = %d/%d, want the defaults 2000/1000", rule.Down, rule.Up)
+	}
+	if view, _ := shaper.Peer(peer); len(view.Throttles) != 0 {
+		t.Fatalf("throttles after lifting all = %v, want none", view.Throttles)
+	}
+}
+
+func TestReshapeAfterSetup(t *testing.T) {
+	config := newTestConfig()
+	path := filepath.Join(t.TempDir(), "node.db")
+	backend := NewFakeBackend()
+	shaper := newTestShaper(t, config, backend, path)
+	peer := newTestPeer(t)
+
+	if err := shaper.Apply(peer, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, err := shaper.SetTier(peer, "basic"); err != nil {
+		t.Fatalf("SetTier: %v", err)
+	}
+
+	// The interface was recreated and lost its tree; re-applying restores it
+	if err := backend.Setup(testInterface); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if rules := backend.Rules(testInterface); len(rules) != 0 {
+		t.Fatalf("rules after Setup = %+v, want none", rules)
+	}
+	if err := shaper.Apply(peer, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply after Setup: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 1000 || rule.Up != 500 {
+		t.Fatalf("re-shaped rates = %d/%d, want tier basic 1000/500", rule.Down, rule.Up)
+	}
+
+	// A restarted node sets up a fresh tree and keeps the tier assignment
+	shaper.store.Close()
+	restarted := newTestShaper(t, config, backend, path)
+	if rules := backend.Rules(testInterface); len(rules) != 0 {
+		t.Fatalf("rules after restart = %+v, want none until peers are applied", rules)
+	}
+	if err := restarted.Apply(peer, []string{"10.8.0.2/32"}); err != nil {
+		t.Fatalf("Apply after restart: %v", err)
+	}
+	if rule := onlyRule(t, backend); rule.Down != 1000 || rule.Up != 500 {
+		t.Fatalf("rates after restart = %d/%d, want tier basic 1000/500", rule.Down, rule.Up)
+	}
+}
+
+func TestNewShaperRejectsBadTiers(t *testing.T) {
+	tests := map[string]*types.NodeConfig{
+		"malformed tier": {WGInterface: testInterface, ShapingTiers: []string{"basic:1000"}},
+		"bad rate":       {WGInterface: testInterface, ShapingTiers: []string{"basic:fast:500"}},
+		"duplicate tier": {WGInterface: testInterface, ShapingTiers: []string{"basic:1:1", "basic:2:2"}},
+		"unknown default": {
+			WGInterface:        testInterface,
+			ShapingTiers:       []string{"basic:1:1"},
+			ShapingDefaultTier: "pro",
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, err := store.Open(filepath.Join(t.TempDir(), "node.db"))
+			if err != nil {
+				t.Fatalf("failed to open store: %v", err)
+			}
+			defer db.Close()
+
+			if _, err := NewShaper(config, NewFakeBackend(), db, logrus.New()); err == nil {
+				t.Fatal("NewShaper succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store is a small embedded key/value database for node state that must
+// survive restarts. Values are stored as JSON documents grouped in buckets.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the database file at path
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Put stores value under key in bucket, replacing any existing value
+func (s *Store) Put(bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// Get loads the value stored under key into value. It reports whether the key exists.
+func (s *Store) Get(bucket, key string, value interface{}) (bool, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(key)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil || data == nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+
+	return true, nil
+}
+
+// Delete removes key from bucket. Deleting a missing key is not an error.
+func (s *Store) Delete(bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// ForEach calls fn for every entry in bucket in key order. The value slice is
+// only valid for the duration of the call.
+func (s *Store) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,390 @@
+package tickets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dvpn-node/internal/billing"
+	"dvpn-node/internal/blockchain"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+	"dvpn-node/internal/wireguard"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	ticketsBucket = "tickets"
+
+	// DomainName and DomainVersion identify the EIP-712 signing domain
+	DomainName    = "dVPN"
+	DomainVersion = "1"
+
+	// maxClockSkew is how far in the future a ticket timestamp may be
+	maxClockSkew = 5 * time.Minute
+
+	// bandwidthTolerance is how much more than the node measured a ticket
+	// may acknowledge, since clients count traffic on their own interface
+	bandwidthTolerance = 0.05
+)
+
+var (
+	// ErrInvalidTicket is returned for malformed tickets
+	ErrInvalidTicket = errors.New("invalid ticket")
+
+	// ErrInvalidSignature is returned when a ticket signature cannot be verified
+	ErrInvalidSignature = errors.New("invalid ticket signature")
+
+	// ErrUnknownPeer is returned for tickets about a peer the node does not serve
+	ErrUnknownPeer = errors.New("unknown peer")
+
+	// ErrNoPayer is returned for tickets about a peer no payer is billed for
+	ErrNoPayer = errors.New("peer has no payer")
+
+	// ErrStaleTicket is returned when a ticket does not supersede the stored one
+	ErrStaleTicket = errors.New("ticket is older than the latest ticket for this peer")
+
+	// ErrTicketNotFound is returned when a peer has no ticket
+	ErrTicketNotFound = errors.New("ticket not found")
+)
+
+// ticketTypes are the EIP-712 types of a payment ticket. The fields follow
+// NodeRegistry.PaymentTicket, plus the WireGuard key of the peer so a ticket
+// cannot be replayed for another connection.
+var ticketTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"PaymentTicket": {
+		{Name: "node", Type: "address"},
+		{Name: "peer", Type: "bytes32"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "bandwidth", Type: "uint256"},
+		{Name: "timestamp", Type: "uint256"},
+	},
+}
+
+// Wallet provides the address of the node wallet tickets are signed for
+type Wallet interface {
+	GetWalletAddress() string
+}
+
+// PeerUsage provides the metered usage tickets are checked against
+type PeerUsage interface {
+	UpdatePeerStats() error
+	GetPeer(publicKey string) (*types.Peer, bool)
+	Usage(publicKey string) (*types.PeerUsage, bool)
+}
+
+// Payers provides the billing account, and so the payer, of a peer
+type Payers interface {
+	Account(publicKey string) (*types.BillingAccount, error)
+}
+
+// Service verifies and stores the payment tickets clients sign for the
+// bandwidth they use. Tickets must be signed by the payer billed for the
+// peer. They are cumulative over a peer connection, so only the latest one
+// per connection is kept, together with how much of it has already been
+// settled. A peer that reconnects starts a new record.
+type Service struct {
+	wallet    Wallet
+	wireguard PeerUsage
+	billing   Payers
+	store     *store.Store
+	logger    *logrus.Logger
+	domain    apitypes.TypedDataDomain
+
+	mu sync.Mutex
+}
+
+// NewService creates a ticket service for the node wallet
+func NewService(blockchain *blockchain.BlockchainService, wireguard *wireguard.WireGuardService, billing *billing.Engine, db *store.Store, logger *logrus.Logger) *Service {
+	return &Service{
+		wallet:    blockchain,
+		wireguard: wireguard,
+		billing:   billing,
+		store:     db,
+		logger:    logger,
+		domain: apitypes.TypedDataDomain{
+			Name:              DomainName,
+			Version:           DomainVersion,
+			ChainId:           (*math.HexOrDecimal256)(blockchain.ChainID()),
+			VerifyingContract: blockchain.NodeRegistryAddress(),
+		},
+	}
+}
+
+// TypedData returns the EIP-712 typed data clients sign for ticket
+func (s *Service) TypedData(ticket *types.PaymentTicket) (apitypes.TypedData, error) {
+	peer, err := wgtypes.ParseKey(ticket.Peer)
+	if err != nil {
+		return apitypes.TypedData{}, fmt.Errorf("%w: peer must be a WireGuard public key", ErrInvalidTicket)
+	}
+
+	amount, ok := new(big.Int).SetString(ticket.Amount, 10)
+	if !ok || amount.Sign() < 0 {
+		return apitypes.TypedData{}, fmt.Errorf("%w: amount must be a non-negative integer", ErrInvalidTicket)
+	}
+
+	if !common.IsHexAddress(ticket.Node) {
+		return apitypes.TypedData{}, fmt.Errorf("%w: node must be an address", ErrInvalidTicket)
+	}
+
+	return apitypes.TypedData{
+		Types:       ticketTypes,
+		PrimaryType: "PaymentTicket",
+		Domain:      s.domain,
+		Message: apitypes.TypedDataMessage{
+			"node":      common.HexToAddress(ticket.Node).Hex(),
+			"peer":      hexutil.Encode(peer[:]),
+			"amount":    amount.String(),
+			"bandwidth": new(big.Int).SetUint64(ticket.Bandwidth).String(),
+			"timestamp": new(big.Int).SetUint64(ticket.Timestamp).String(),
+		},
+	}, nil
+}
+
+// Submit verifies a ticket and stores it as the latest ticket for its peer
+func (s *Service) Submit(ticket types.PaymentTicket) (*types.TicketRecord, error) {
+	wallet := s.wallet.GetWalletAddress()
+	if !strings.EqualFold(ticket.Node, wallet) {
+		return nil, fmt.Errorf("%w: ticket is for node %s, not %s", ErrInvalidTicket, ticket.Node, wallet)
+	}
+
+	client, err := s.recover(&ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Unix(int64(ticket.Timestamp), 0).After(time.Now().Add(maxClockSkew)) {
+		return nil, fmt.Errorf("%w: timestamp is in the future", ErrInvalidTicket)
+	}
+
+	// Refresh counters so the ticket is checked against current usage
+	if err := s.wireguard.UpdatePeerStats(); err != nil {
+		s.logger.Warnf("Failed to update peer stats, using last known usage: %v", err)
+	}
+
+	// Device counters restart when a peer is re-created, while tickets
+	// acknowledge the whole session
+	usage, exists := s.wireguard.Usage(ticket.Peer)
+	if _, connected := s.wireguard.GetPeer(ticket.Peer); !exists || !connected {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPeer, ticket.Peer)
+	}
+
+	// Peer keys are public, so only the payer's signature ties a ticket to
+	// the client that pays for the peer
+	account, err := s.billing.Account(ticket.Peer)
+	if errors.Is(err, billing.ErrAccountNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNoPayer, ticket.Peer)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(account.Payer, client.Hex()) {
+		return nil, fmt.Errorf("%w: signed by %s, not the payer %s", ErrInvalidSignature, client.Hex(), account.Payer)
+	}
+
+	measured := uint64(usage.SessionBytes)
+	if limit := float64(measured) * (1 + bandwidthTolerance); float64(ticket.Bandwidth) > limit {
+		return nil, fmt.Errorf("%w: acknowledges %d bytes but only %d were measured", ErrInvalidTicket, ticket.Bandwidth, measured)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recordKey(ticket.Peer, usage.SessionID)
+	record := types.TicketRecord{Session: usage.SessionID, SettledAmount: "0"}
+	found, err := s.store.Get(ticketsBucket, key, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		if !strings.EqualFold(record.Client, client.Hex()) {
+			return nil, fmt.Errorf("%w: peer tickets are signed by %s", ErrInvalidSignature, record.Client)
+		}
+
+		previous, _ := new(big.Int).SetString(record.Ticket.Amount, 10)
+		amount, _ := new(big.Int).SetString(ticket.Amount, 10)
+		if ticket.Timestamp <= record.Ticket.Timestamp || ticket.Bandwidth < record.Ticket.Bandwidth || amount.Cmp(previous) < 0 {
+			return nil, ErrStaleTicket
+		}
+	}
+
+	record.Ticket = ticket
+	record.Client = client.Hex()
+	record.MeasuredBandwidth = measured
+	record.ReceivedAt = time.Now().Unix()
+
+	if err := s.store.Put(ticketsBucket, key, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Debugf("Ticket from %s for peer %s: %d bytes, %s tokens", record.Client, ticket.Peer, ticket.Bandwidth, ticket.Amount)
+	return &record, nil
+}
+
+// Latest returns the latest ticket for a peer, from its most recent connection
+func (s *Service) Latest(peer string) (*types.TicketRecord, error) {
+	records, err := s.peerRecords(peer)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTicketNotFound, peer)
+	}
+
+	latest := &records[0]
+	for i := range records[1:] {
+		if record := &records[i+1]; record.Ticket.Timestamp > latest.Ticket.Timestamp {
+			latest = record
+		}
+	}
+	return latest, nil
+}
+
+// Records returns the latest ticket of every peer connection
+func (s *Service) Records() ([]types.TicketRecord, error) {
+	return s.peerRecords("")
+}
+
+// peerRecords returns the ticket records of peer, or of every peer if peer is empty
+func (s *Service) peerRecords(peer string) ([]types.TicketRecord, error) {
+	var records []types.TicketRecord
+	err := s.store.ForEach(ticketsBucket, func(key string, value []byte) error {
+		if peer != "" && !strings.HasPrefix(key, peer+"/") {
+			return nil
+		}
+		var record types.TicketRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// Settlements returns the unsettled part of every peer's latest ticket. As
+// NodeRegistry.processPayment adds the ticket amount and bandwidth to the
+// node totals, each settlement carries only the increase since the last one,
+// along with the signed cumulative values and the settled values it was
+// derived from.
+func (s *Service) Settlements() ([]types.TicketSettlement, error) {
+	records, err := s.Records()
+	if err != nil {
+		return nil, err
+	}
+
+	var settlements []types.TicketSettlement
+	for _, record := range records {
+		amount, _ := new(big.Int).SetString(record.Ticket.Amount, 10)
+		settled, _ := new(big.Int).SetString(record.SettledAmount, 10)
+		delta := new(big.Int).Sub(amount, settled)
+
+		if delta.Sign() <= 0 && record.Ticket.Bandwidth <= record.SettledBandwidth {
+			continue
+		}
+
+		settlements = append(settlements, types.TicketSettlement{
+			Peer:      record.Ticket.Peer,
+			Node:      record.Ticket.Node,
+			Sender:    record.Client,
+			Amount:    delta.String(),
+			Bandwidth: record.Ticket.Bandwidth - record.SettledBandwidth,
+			Timestamp: record.Ticket.Timestamp,
+			Signature: record.Ticket.Signature,
+
+			TicketAmount:     record.Ticket.Amount,
+			TicketBandwidth:  record.Ticket.Bandwidth,
+			SettledAmount:    settled.String(),
+			SettledBandwidth: record.SettledBandwidth,
+		})
+	}
+
+	return settlements, nil
+}
+
+// MarkSettled records that the ticket of peer with the given timestamp has
+// been settled on-chain
+func (s *Service) MarkSettled(peer string, timestamp uint64) (*types.TicketRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.peerRecords(peer)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTicketNotFound, peer)
+	}
+
+	for i := range records {
+		record := &records[i]
+		if record.Ticket.Timestamp != timestamp {
+			continue
+		}
+
+		record.SettledAmount = record.Ticket.Amount
+		record.SettledBandwidth = record.Ticket.Bandwidth
+
+		if err := s.store.Put(ticketsBucket, recordKey(peer, record.Session), record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	// A newer ticket may have arrived since the settlement was exported
+	return nil, fmt.Errorf("%w: no ticket of peer %s has timestamp %d", ErrStaleTicket, peer, timestamp)
+}
+
+// recordKey returns the store key of the tickets of one connection of peer
+func recordKey(peer string, session uint64) string {
+	return peer + "/" + strconv.FormatUint(session, 10)
+}
+
+// recover returns the address that signed ticket
+func (s *Service) recover(ticket *types.PaymentTicket) (common.Address, error) {
+	typedData, err := s.TypedData(ticket)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidTicket, err)
+	}
+
+	signature, err := hexutil.Decode(ticket.Signature)
+	if err != nil || len(signature) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	sig := append([]byte(nil), signature...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
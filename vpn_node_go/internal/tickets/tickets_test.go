@@ -0,0 +1,287 @@
+package tickets
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dvpn-node/internal/billing"
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testPeer     = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	testNode     = "0x00000000000000000000000000000000000000bB"
+	testRegistry = "0x00000000000000000000000000000000000000cc"
+	testChainID  = 1337
+	otherAddress = "0x0000000000000000000000000000000000000001"
+)
+
+type fakeWallet struct{}
+
+func (fakeWallet) GetWalletAddress() string { return testNode }
+
+// fakeUsage meters one peer with the usage set by the test
+type fakeUsage struct {
+	usage *types.PeerUsage
+}
+
+func (f *fakeUsage) UpdatePeerStats() error { return nil }
+
+func (f *fakeUsage) GetPeer(publicKey string) (*types.Peer, bool) {
+	if f.usage == nil || publicKey != testPeer {
+		return nil, false
+	}
+	return &types.Peer{PublicKey: publicKey}, true
+}
+
+func (f *fakeUsage) Usage(publicKey string) (*types.PeerUsage, bool) {
+	if f.usage == nil || publicKey != testPeer {
+		return nil, false
+	}
+	snapshot := *f.usage
+	return &snapshot, true
+}
+
+// fakePayers bills the test peer to payer, if set
+type fakePayers struct {
+	payer string
+}
+
+func (f *fakePayers) Account(publicKey string) (*types.BillingAccount, error) {
+	if f.payer == "" || publicKey != testPeer {
+		return nil, billing.ErrAccountNotFound
+	}
+	return &types.BillingAccount{PeerPublicKey: publicKey, Payer: f.payer}, nil
+}
+
+type testEnv struct {
+	service *Service
+	usage   *fakeUsage
+	payers  *fakePayers
+	payer   *ecdsa.PrivateKey
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func testDomain(chainID int64, registry string) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              DomainName,
+		Version:           DomainVersion,
+		ChainId:           (*math.HexOrDecimal256)(big.NewInt(chainID)),
+		VerifyingContract: common.HexToAddress(registry).Hex(),
+	}
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "node.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	payer := newTestKey(t)
+	env := &testEnv{
+		usage:  &fakeUsage{usage: &types.PeerUsage{SessionBytes: 1000, SessionID: 1}},
+		payers: &fakePayers{payer: crypto.PubkeyToAddress(payer.PublicKey).Hex()},
+		payer:  payer,
+	}
+	env.service = &Service{
+		wallet:    fakeWallet{},
+		wireguard: env.usage,
+		billing:   env.payers,
+		store:     db,
+		logger:    logger,
+		domain:    testDomain(testChainID, testRegistry),
+	}
+	return env
+}
+
+func newTicket(amount string, bandwidth, timestamp uint64) types.PaymentTicket {
+	return types.PaymentTicket{
+		Node:      testNode,
+		Peer:      testPeer,
+		Amount:    amount,
+		Bandwidth: bandwidth,
+		Timestamp: timestamp,
+	}
+}
+
+// sign signs ticket with key over domain, with V as 27/28 like wallets do
+func sign(t *testing.T, key *ecdsa.PrivateKey, domain apitypes.TypedDataDomain, ticket types.PaymentTicket) types.PaymentTicket {
+	t.Helper()
+	service := &Service{domain: domain}
+	typedData, err := service.TypedData(&ticket)
+	if err != nil {
+		t.Fatalf("TypedData: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("TypedDataAndHash: %v", err)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	ticket.Signature = hexutil.Encode(signature)
+	return ticket
+}
+
+func (e *testEnv) sign(t *testing.T, ticket types.PaymentTicket) types.PaymentTicket {
+	t.Helper()
+	return sign(t, e.payer, e.service.domain, ticket)
+}
+
+func now() uint64 {
+	return uint64(time.Now().Unix())
+}
+
+func TestSubmitAcceptsValidTicket(t *testing.T) {
+	env := newTestEnv(t)
+	ticket := env.sign(t, newTicket("100", 1000, now()))
+
+	record, err := env.service.Submit(ticket)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if record.Client != env.payers.payer || record.MeasuredBandwidth != 1000 {
+		t.Fatalf("record = client %s, measured %d; want %s, 1000", record.Client, record.MeasuredBandwidth, env.payers.payer)
+	}
+
+	latest, err := env.service.Latest(testPeer)
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if latest.Ticket.Signature != ticket.Signature {
+		t.Fatal("Latest did not return the submitted ticket")
+	}
+}
+
+func TestSubmitRejects(t *testing.T) {
+	env := newTestEnv(t)
+	ts := now()
+
+	for _, tt := range []struct {
+		name   string
+		ticket types.PaymentTicket
+		want   error
+	}{
+		{"signed by another wallet", sign(t, newTestKey(t), env.service.domain, newTicket("100", 1000, ts)), ErrInvalidSignature},
+		{"signed for another chain", sign(t, env.payer, testDomain(1, testRegistry), newTicket("100", 1000, ts)), ErrInvalidSignature},
+		{"signed for another registry", sign(t, env.payer, testDomain(testChainID, otherAddress), newTicket("100", 1000, ts)), ErrInvalidSignature},
+		{"for another node", env.sign(t, types.PaymentTicket{Node: otherAddress, Peer: testPeer, Amount: "100", Bandwidth: 1000, Timestamp: ts}), ErrInvalidTicket},
+		{"timestamp in the future", env.sign(t, newTicket("100", 1000, ts+uint64(time.Hour/time.Second))), ErrInvalidTicket},
+		{"bandwidth above tolerance", env.sign(t, newTicket("100", 1051, ts)), ErrInvalidTicket},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.service.Submit(tt.ticket); !errors.Is(err, tt.want) {
+				t.Fatalf("Submit = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Within the tolerance is accepted
+	if _, err := env.service.Submit(env.sign(t, newTicket("100", 1050, ts))); err != nil {
+		t.Fatalf("Submit within tolerance: %v", err)
+	}
+}
+
+func TestSubmitRejectsPeerWithoutPayer(t *testing.T) {
+	env := newTestEnv(t)
+	env.payers.payer = ""
+
+	if _, err := env.service.Submit(env.sign(t, newTicket("100", 1000, now()))); !errors.Is(err, ErrNoPayer) {
+		t.Fatalf("Submit = %v, want ErrNoPayer", err)
+	}
+}
+
+func TestSubmitRejectsStaleTickets(t *testing.T) {
+	env := newTestEnv(t)
+	ts := now() - 60
+
+	if _, err := env.service.Submit(env.sign(t, newTicket("100", 500, ts))); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		ticket types.PaymentTicket
+	}{
+		{"same timestamp", newTicket("200", 600, ts)},
+		{"older timestamp", newTicket("200", 600, ts-1)},
+		{"less bandwidth", newTicket("200", 400, ts+1)},
+		{"smaller amount", newTicket("99", 600, ts+1)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.service.Submit(env.sign(t, tt.ticket)); !errors.Is(err, ErrStaleTicket) {
+				t.Fatalf("Submit = %v, want ErrStaleTicket", err)
+			}
+		})
+	}
+
+	if _, err := env.service.Submit(env.sign(t, newTicket("200", 600, ts+1))); err != nil {
+		t.Fatalf("Submit of a newer ticket: %v", err)
+	}
+}
+
+func TestSubmitAfterReconnect(t *testing.T) {
+	env := newTestEnv(t)
+	ts := now() - 60
+
+	if _, err := env.service.Submit(env.sign(t, newTicket("100", 1000, ts))); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// The same key connects again, paid by someone else, and its usage
+	// starts over
+	env.payer = newTestKey(t)
+	env.payers.payer = crypto.PubkeyToAddress(env.payer.PublicKey).Hex()
+	env.usage.usage = &types.PeerUsage{SessionBytes: 200, SessionID: 2}
+
+	record, err := env.service.Submit(env.sign(t, newTicket("10", 200, ts+1)))
+	if err != nil {
+		t.Fatalf("Submit after reconnect: %v", err)
+	}
+	if record.Client != env.payers.payer || record.Session != 2 {
+		t.Fatalf("record = client %s, session %d; want %s, 2", record.Client, record.Session, env.payers.payer)
+	}
+
+	// The first connection is still settled on its own
+	settlements, err := env.service.Settlements()
+	if err != nil {
+		t.Fatalf("Settlements: %v", err)
+	}
+	if len(settlements) != 2 {
+		t.Fatalf("got %d settlements, want 2", len(settlements))
+	}
+	if _, err := env.service.MarkSettled(testPeer, ts); err != nil {
+		t.Fatalf("MarkSettled of the first connection: %v", err)
+	}
+	if latest, err := env.service.Latest(testPeer); err != nil || latest.Session != 2 {
+		t.Fatalf("Latest = %+v, %v; want the ticket of session 2", latest, err)
+	}
+}
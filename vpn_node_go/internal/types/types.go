@@ -8,27 +8,132 @@ import (
 
 // NodeConfig holds the configuration for the VPN node
 type NodeConfig struct {
-	RPCURL           string `env:"RPC_URL" envDefault:"https://testnet-rpc.mawari.network"`
-	PrivateKey       string `env:"PRIVATE_KEY"`
-	TokenAddress     string `env:"TOKEN_ADDRESS"`
-	NodeRegistryAddr string `env:"NODE_REGISTRY_ADDRESS"`
-	PaymentHubAddr   string `env:"PAYMENT_HUB_ADDRESS"`
+	RPCURL           string   `env:"RPC_URL" envDefault:"https://testnet-rpc.mawari.network"`
+	RPCURLs          []string `env:"RPC_URLS"` // comma-separated, overrides RPC_URL
+	PrivateKey       string   `env:"PRIVATE_KEY"`
+	TokenAddress     string   `env:"TOKEN_ADDRESS"`
+	NodeRegistryAddr string   `env:"NODE_REGISTRY_ADDRESS"`
+	PaymentHubAddr   string   `env:"PAYMENT_HUB_ADDRESS"`
+
+	// RPC Failover
+	RPCTimeout        time.Duration `env:"RPC_TIMEOUT" envDefault:"10s"`
+	RPCHealthInterval time.Duration `env:"RPC_HEALTH_INTERVAL" envDefault:"15s"`
+	RPCMaxBlockLag    uint64        `env:"RPC_MAX_BLOCK_LAG" envDefault:"5"`
+
+	// Signing (one of remote signer, keystore or PrivateKey)
+	KeystorePath         string `env:"KEYSTORE_PATH"`
+	KeystorePasswordFile string `env:"KEYSTORE_PASSWORD_FILE"`
+	RemoteSignerURL      string `env:"REMOTE_SIGNER_URL"`
+	RemoteSignerAddress  string `env:"REMOTE_SIGNER_ADDRESS"`
+	RemoteSignerMethod   string `env:"REMOTE_SIGNER_METHOD" envDefault:"eth_signTransaction"`
+
+	// Transaction Management
+	TxConfirmations  uint64        `env:"TX_CONFIRMATIONS" envDefault:"1"`
+	TxStuckTimeout   time.Duration `env:"TX_STUCK_TIMEOUT" envDefault:"2m"`
+	TxFeeBumpPercent int           `env:"TX_FEE_BUMP_PERCENT" envDefault:"15"`
+	TxMaxFeeCap      string        `env:"TX_MAX_FEE_CAP"` // wei, empty for no cap
 
 	// WireGuard Configuration
-	WGInterface  string `env:"WG_INTERFACE" envDefault:"wg0"`
-	WGPort       int    `env:"WG_PORT" envDefault:"51820"`
-	WGPrivateKey string `env:"WG_PRIVATE_KEY"`
-	WGPublicKey  string `env:"WG_PUBLIC_KEY"`
-	WGSubnet     string `env:"WG_SUBNET" envDefault:"10.0.0.1/24"`
+	WGInterface    string   `env:"WG_INTERFACE" envDefault:"wg0"`
+	WGPort         int      `env:"WG_PORT" envDefault:"51820"`
+	WGPrivateKey   string   `env:"WG_PRIVATE_KEY"`
+	WGPublicKey    string   `env:"WG_PUBLIC_KEY"`
+	WGSubnet       string   `env:"WG_SUBNET" envDefault:"10.0.0.1/24"`
+	WGAdoptOrphans bool     `env:"WG_ADOPT_ORPHANS" envDefault:"false"` // keep unknown device peers on startup
+	WGBackend      string   `env:"WG_BACKEND" envDefault:"auto"`        // auto, kernel or userspace
+	WGMTU          int      `env:"WG_MTU" envDefault:"1420"`
+	WGRoutes       []string `env:"WG_ROUTES"` // extra CIDRs routed through the interface
+
+	// Peer Connectivity
+	WGHandshakeTimeout time.Duration `env:"WG_HANDSHAKE_TIMEOUT" envDefault:"3m"` // handshake age after which a peer is idle
+	WGIdleTimeout      time.Duration `env:"WG_IDLE_TIMEOUT" envDefault:"5m"`      // time without traffic after which a peer is idle
+	WGStaleTimeout     time.Duration `env:"WG_STALE_TIMEOUT" envDefault:"30m"`    // handshake age after which a peer is stale
+
+	// Peer Reaper
+	WGReapInterval    time.Duration `env:"WG_REAP_INTERVAL" envDefault:"1m"`
+	WGReapIdleTimeout time.Duration `env:"WG_REAP_IDLE_TIMEOUT" envDefault:"1h"` // 0 disables
+	WGMaxPeerLifetime time.Duration `env:"WG_MAX_PEER_LIFETIME" envDefault:"0"`  // 0 disables
+
+	// Data Quotas (NodeBandwidth is the node-wide daily cap)
+	QuotaEnabled      bool   `env:"QUOTA_ENABLED" envDefault:"false"`
+	QuotaSessionBytes int64  `env:"QUOTA_SESSION_BYTES" envDefault:"0"` // per peer session, 0 disables
+	QuotaDailyBytes   int64  `env:"QUOTA_DAILY_BYTES" envDefault:"0"`   // per peer per UTC day, 0 disables
+	QuotaAction       string `env:"QUOTA_ACTION" envDefault:"disconnect"`
+	QuotaThrottleRate uint64 `env:"QUOTA_THROTTLE_RATE" envDefault:"131072"` // bytes per second
+
+	// Traffic Shaping (rates in bytes per second, 0 is unlimited)
+	ShapingEnabled     bool     `env:"SHAPING_ENABLED" envDefault:"false"`
+	ShapingBackend     string   `env:"SHAPING_BACKEND" envDefault:"netlink"` // netlink or fake
+	ShapingDefaultUp   uint64   `env:"SHAPING_DEFAULT_UP" envDefault:"0"`
+	ShapingDefaultDown uint64   `env:"SHAPING_DEFAULT_DOWN" envDefault:"0"`
+	ShapingTiers       []string `env:"SHAPING_TIERS"`        // name:down:up entries
+	ShapingDefaultTier string   `env:"SHAPING_DEFAULT_TIER"` // tier of peers without one
+
+	// Exit Firewall (NAT, forwarding and peer isolation)
+	FirewallEnabled      bool     `env:"FIREWALL_ENABLED" envDefault:"false"`
+	FirewallEgress       string   `env:"FIREWALL_EGRESS_INTERFACE"` // empty uses the default route
+	FirewallIsolatePeers bool     `env:"FIREWALL_ISOLATE_PEERS" envDefault:"true"`
+	FirewallBlockedPorts []string `env:"FIREWALL_BLOCKED_PORTS"` // node TCP ports closed to peers, besides API_PORT
+
+	// Client Sessions
+	NodePublicEndpoint    string        `env:"NODE_PUBLIC_ENDPOINT"` // host:port clients connect to
+	ClientDNS             []string      `env:"CLIENT_DNS" envDefault:"1.1.1.1"`
+	ClientKeepalive       int           `env:"CLIENT_KEEPALIVE" envDefault:"25"`  // seconds, 0 disables
+	ClientSplitAllowedIPs []string      `env:"CLIENT_SPLIT_ALLOWED_IPS"`          // extra routes for split tunnels
+	SessionMinAmount      string        `env:"SESSION_MIN_AMOUNT" envDefault:"0"` // minimum unwithdrawn stream balance (wei)
+	SessionCheckInterval  time.Duration `env:"SESSION_CHECK_INTERVAL" envDefault:"30s"`
+
+	// Automatic Stream Withdrawals
+	WithdrawEnabled       bool          `env:"WITHDRAW_ENABLED" envDefault:"true"`
+	WithdrawInterval      time.Duration `env:"WITHDRAW_INTERVAL" envDefault:"5m"`
+	WithdrawThreshold     string        `env:"WITHDRAW_THRESHOLD" envDefault:"10000000000000000000"` // 10 tokens in wei
+	WithdrawEndWindow     time.Duration `env:"WITHDRAW_END_WINDOW" envDefault:"1h"`
+	WithdrawMaxGasShare   float64       `env:"WITHDRAW_MAX_GAS_SHARE" envDefault:"0.05"`
+	WithdrawGasTokenRate  string        `env:"WITHDRAW_GAS_TOKEN_RATE" envDefault:"1"` // tokens per unit of gas currency
+	WithdrawStartBlock    uint64        `env:"WITHDRAW_START_BLOCK" envDefault:"0"`
+	WithdrawConfirmations uint64        `env:"WITHDRAW_CONFIRMATIONS" envDefault:"12"` // reorg depth for stream discovery
+
+	// Event Indexer
+	IndexerEnabled       bool          `env:"INDEXER_ENABLED" envDefault:"true"`
+	IndexerStartBlock    uint64        `env:"INDEXER_START_BLOCK" envDefault:"0"`
+	IndexerConfirmations uint64        `env:"INDEXER_CONFIRMATIONS" envDefault:"12"`
+	IndexerPollInterval  time.Duration `env:"INDEXER_POLL_INTERVAL" envDefault:"15s"`
+
+	// Alert Webhooks
+	WebhookURLs       []string      `env:"WEBHOOK_URLS"` // comma-separated
+	WebhookSecret     string        `env:"WEBHOOK_SECRET"`
+	WebhookMaxRetries int           `env:"WEBHOOK_MAX_RETRIES" envDefault:"5"`
+	WebhookTimeout    time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+
+	// Storage
+	DataDir string `env:"DATA_DIR" envDefault:"./data"`
 
 	// API Configuration
-	APIPort         int  `env:"API_PORT" envDefault:"3000"`
-	EnableWebSocket bool `env:"ENABLE_WEBSOCKET" envDefault:"true"`
+	APIPort         int    `env:"API_PORT" envDefault:"3000"`
+	EnableWebSocket bool   `env:"ENABLE_WEBSOCKET" envDefault:"true"`
+	AdminToken      string `env:"ADMIN_TOKEN"` // bearer token for operator-only routes, which are disabled without it
 
 	// Node Metadata
 	NodeLocation  string `env:"NODE_LOCATION" envDefault:"Toronto, Canada"`
 	NodeBandwidth int64  `env:"NODE_BANDWIDTH" envDefault:"1000000000"`        // 1GB in bytes
 	MinStake      string `env:"MIN_STAKE" envDefault:"1000000000000000000000"` // 1000 tokens in wei
+
+	// Heartbeat
+	HeartbeatEnabled   bool          `env:"HEARTBEAT_ENABLED" envDefault:"true"`
+	HeartbeatInterval  time.Duration `env:"HEARTBEAT_INTERVAL" envDefault:"1h"`
+	HeartbeatBatchSize int           `env:"HEARTBEAT_BATCH_SIZE" envDefault:"6"`
+	HeartbeatURLs      []string      `env:"HEARTBEAT_URLS"` // comma-separated collector endpoints
+
+	// Metered Billing
+	BillingEnabled        bool          `env:"BILLING_ENABLED" envDefault:"true"`
+	BillingInterval       time.Duration `env:"BILLING_INTERVAL" envDefault:"1m"`
+	BillingPricePerGB     string        `env:"BILLING_PRICE_PER_GB" envDefault:"1000000000000000000"` // wei per 10^9 bytes
+	BillingGracePeriod    time.Duration `env:"BILLING_GRACE_PERIOD" envDefault:"5m"`
+	BillingUnderpayAction string        `env:"BILLING_UNDERPAY_ACTION" envDefault:"remove"` // warn, throttle or remove
+	BillingThrottleRate   uint64        `env:"BILLING_THROTTLE_RATE" envDefault:"131072"`   // bytes per second
+
+	// Node Exit
+	ExitGracePeriod time.Duration `env:"EXIT_GRACE_PERIOD" envDefault:"10m"`
 }
 
 // NodeInfo represents a node in the registry
@@ -43,6 +148,56 @@ type NodeInfo struct {
 	TotalEarnings          string         `json:"totalEarnings"`
 }
 
+// StakeInfo represents the stake the node holds in the registry
+type StakeInfo struct {
+	Stake    string `json:"stake"`
+	MinStake string `json:"minStake"`
+	IsActive bool   `json:"isActive"`
+}
+
+// ExitStatus represents the progress of retiring the node
+type ExitStatus struct {
+	Stage            string `json:"stage"` // draining, withdrawing, unregistering, completed or failed
+	StartedAt        int64  `json:"startedAt"`
+	DrainDeadline    int64  `json:"drainDeadline"`
+	UnregisterTxHash string `json:"unregisterTxHash,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Heartbeat is a liveness attestation signed by the node wallet
+type Heartbeat struct {
+	Node           string `json:"node"`
+	ChainID        string `json:"chainId"`
+	Registry       string `json:"registry"`
+	Sequence       uint64 `json:"sequence"`
+	Timestamp      int64  `json:"timestamp"`
+	ConnectedPeers int    `json:"connectedPeers"`
+	BytesRx        int64  `json:"bytesRx"`
+	BytesTx        int64  `json:"bytesTx"`
+	Signature      string `json:"signature"` // EIP-191 signature of the heartbeat message
+}
+
+// HeartbeatBatch is the body posted to heartbeat collectors
+type HeartbeatBatch struct {
+	Node       string      `json:"node"`
+	Heartbeats []Heartbeat `json:"heartbeats"`
+}
+
+// HeartbeatStatus represents the state of the heartbeat service
+type HeartbeatStatus struct {
+	Healthy        bool       `json:"healthy"`
+	UnhealthySince int64      `json:"unhealthySince,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastHeartbeat  *Heartbeat `json:"lastHeartbeat,omitempty"`
+	Pending        int        `json:"pending"`
+}
+
+// RegistrationResult holds the transactions sent to register the node
+type RegistrationResult struct {
+	ApproveTxHash  string `json:"approveTxHash,omitempty"`
+	RegisterTxHash string `json:"registerTxHash,omitempty"`
+}
+
 // Peer represents a WireGuard peer/client
 type Peer struct {
 	PublicKey  string    `json:"publicKey"`
@@ -51,7 +206,180 @@ type Peer struct {
 	LastSeen   time.Time `json:"lastSeen"`
 	BytesRx    int64     `json:"bytesRx"`
 	BytesTx    int64     `json:"bytesTx"`
-	IsActive   bool      `json:"isActive"`
+	IsActive   bool      `json:"isActive"` // state is connected
+	CreatedAt  time.Time `json:"createdAt"`
+
+	// Generation changes each time the peer is created on the device, which
+	// restarts its byte counters from zero
+	Generation uint64 `json:"generation,omitempty"`
+
+	State         string    `json:"state"` // pending, connected, idle or stale
+	LastHandshake time.Time `json:"lastHandshake"`
+	LastActivity  time.Time `json:"lastActivity"` // when the byte counters last moved
+
+	Quota *PeerQuota `json:"quota,omitempty"`
+}
+
+// PeerUsage is the metered data usage of a peer
+type PeerUsage struct {
+	SessionBytes int64  `json:"sessionBytes"`        // since the peer was added
+	SessionID    uint64 `json:"sessionId,omitempty"` // changes whenever SessionBytes restarts
+	DailyBytes   int64  `json:"dailyBytes"`
+	Day          string `json:"day"` // UTC day DailyBytes belongs to
+	Exceeded     string `json:"exceeded,omitempty"`
+
+	// Last counter values read from the interface, and the peer generation
+	// they belong to
+	CounterRx  int64  `json:"counterRx"`
+	CounterTx  int64  `json:"counterTx"`
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// NodeUsage is the node-wide data usage of a UTC day
+type NodeUsage struct {
+	Day      string `json:"day"`
+	Bytes    int64  `json:"bytes"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+// QuotaLimit is the usage against one data limit
+type QuotaLimit struct {
+	Limit     int64 `json:"limit"` // 0 when unlimited
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining"`
+}
+
+// PeerQuota reports the data limits of a peer; nil limits are disabled
+type PeerQuota struct {
+	Enabled   bool        `json:"enabled"`
+	Session   *QuotaLimit `json:"session,omitempty"`
+	Daily     *QuotaLimit `json:"daily,omitempty"`
+	Prepaid   *QuotaLimit `json:"prepaid,omitempty"`
+	Remaining *int64      `json:"remaining,omitempty"` // lowest remaining of all limits
+	Exceeded  string      `json:"exceeded,omitempty"`  // limit that was hit
+}
+
+// FirewallStatus describes the exit firewall rules installed by the node
+type FirewallStatus struct {
+	Table           string `json:"table"`
+	Subnet          string `json:"subnet"`
+	EgressInterface string `json:"egressInterface"`
+	IsolatePeers    bool   `json:"isolatePeers"`
+	BlockedPorts    []int  `json:"blockedPorts"`
+	Forwarding      bool   `json:"forwarding"`
+	AppliedAt       int64  `json:"appliedAt"`
+}
+
+// ShapingTier is a named pair of rate ceilings in bytes per second
+type ShapingTier struct {
+	Name string `json:"name"`
+	Down uint64 `json:"down"`
+	Up   uint64 `json:"up"`
+}
+
+// PeerShaping is the rate limit applied to a peer. Rates are in bytes per
+// second; 0 is unlimited.
+type PeerShaping struct {
+	PublicKey string   `json:"publicKey"`
+	ClassID   uint16   `json:"classId,omitempty"` // 0 until the peer is on the device
+	Addresses []string `json:"addresses,omitempty"`
+	Tier      string   `json:"tier,omitempty"`
+	Down      uint64   `json:"down"` // effective ceiling
+	Up        uint64   `json:"up"`   // effective ceiling
+
+	// Throttles are the temporary caps set by billing or quotas, by source
+	Throttles map[string]uint64 `json:"throttles,omitempty"`
+}
+
+// ReconcileReport lists the changes made to bring the WireGuard device, the
+// peer store and open sessions back in sync
+type ReconcileReport struct {
+	Timestamp int64    `json:"timestamp"`
+	Restored  []string `json:"restored"` // stored or session peers re-applied to the device
+	Updated   []string `json:"updated"`  // device peers whose allowed IPs were corrected
+	Adopted   []string `json:"adopted"`  // unknown device peers added to the store
+	Removed   []string `json:"removed"`  // unknown device peers removed from the device
+	Released  []string `json:"released"` // address leases without a peer
+	Errors    []string `json:"errors"`
+}
+
+// PaymentTicket is an EIP-712 ticket in which a client acknowledges the
+// cumulative bandwidth it has used through a peer and the amount owed for it
+type PaymentTicket struct {
+	Node      string `json:"node"`
+	Peer      string `json:"peer"` // WireGuard public key of the client
+	Amount    string `json:"amount"`
+	Bandwidth uint64 `json:"bandwidth"` // cumulative bytes
+	Timestamp uint64 `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// TicketRecord is the latest verified ticket for a peer connection
+type TicketRecord struct {
+	Ticket            PaymentTicket `json:"ticket"`
+	Session           uint64        `json:"session"` // SessionID of the peer usage the ticket covers
+	Client            string        `json:"client"`  // address that signed the ticket
+	MeasuredBandwidth uint64        `json:"measuredBandwidth"`
+	SettledAmount     string        `json:"settledAmount"`
+	SettledBandwidth  uint64        `json:"settledBandwidth"`
+	ReceivedAt        int64         `json:"receivedAt"`
+}
+
+// TicketSettlement is the unsettled part of a peer's latest ticket, in the
+// shape of NodeRegistry.PaymentTicket and PaymentHub.Payment (sender = client).
+// Signature covers the cumulative ticket, i.e. TicketAmount, TicketBandwidth
+// and Timestamp; Amount and Bandwidth are those minus the settled values.
+type TicketSettlement struct {
+	Peer             string `json:"peer"`
+	Node             string `json:"node"`
+	Sender           string `json:"sender"`
+	Amount           string `json:"amount"`
+	Bandwidth        uint64 `json:"bandwidth"`
+	Timestamp        uint64 `json:"timestamp"`
+	Signature        string `json:"signature"`
+	TicketAmount     string `json:"ticketAmount"`
+	TicketBandwidth  uint64 `json:"ticketBandwidth"`
+	SettledAmount    string `json:"settledAmount"`
+	SettledBandwidth uint64 `json:"settledBandwidth"`
+}
+
+// IPLease is the set of tunnel addresses assigned to a peer
+type IPLease struct {
+	PublicKey  string   `json:"publicKey"`
+	Addresses  []string `json:"addresses"`
+	AssignedAt int64    `json:"assignedAt"`
+}
+
+// IPPoolStats reports the utilisation of the tunnel address pool
+type IPPoolStats struct {
+	Subnet        string  `json:"subnet"`
+	ServerAddress string  `json:"serverAddress"`
+	Total         int     `json:"total"`
+	Used          int     `json:"used"`
+	Available     int     `json:"available"`
+	Peers         int     `json:"peers"`
+	Utilization   float64 `json:"utilization"` // used / total
+}
+
+// Session is a paid client connection backed by a payment stream
+type Session struct {
+	PublicKey string `json:"publicKey"`
+	Payer     string `json:"payer"`
+	StreamID  string `json:"streamId"`
+	Address   string `json:"address"` // tunnel address of the client
+	CreatedAt int64  `json:"createdAt"`
+	EndsAt    int64  `json:"endsAt"` // end time of the stream
+}
+
+// ClientConfig is the WireGuard configuration a client needs to connect
+type ClientConfig struct {
+	PrivateKey          string   `json:"privateKey,omitempty"` // only when supplied by the client
+	Address             string   `json:"address"`
+	DNS                 []string `json:"dns,omitempty"`
+	ServerPublicKey     string   `json:"serverPublicKey"`
+	Endpoint            string   `json:"endpoint"`
+	AllowedIPs          []string `json:"allowedIPs"`
+	PersistentKeepalive int      `json:"persistentKeepalive"`
 }
 
 // PaymentStream represents a payment stream from a client
@@ -63,6 +391,7 @@ type PaymentStream struct {
 	StartTime uint64 `json:"startTime"`
 	EndTime   uint64 `json:"endTime"`
 	Withdrawn string `json:"withdrawn"`
+	Available string `json:"available"` // currently withdrawable by the recipient
 	IsActive  bool   `json:"isActive"`
 }
 
@@ -74,6 +403,52 @@ type BandwidthUsage struct {
 	Timestamp     time.Time `json:"timestamp"`
 }
 
+// BillingAccount links a WireGuard peer to the wallet paying for it and
+// tracks the cost of its metered usage
+type BillingAccount struct {
+	PeerPublicKey  string `json:"peerPublicKey"`
+	Payer          string `json:"payer"`
+	StreamID       string `json:"streamId"`
+	BytesRx        int64  `json:"bytesRx"` // billed bytes
+	BytesTx        int64  `json:"bytesTx"`
+	Cost           string `json:"cost"`     // accrued cost in wei
+	Streamed       string `json:"streamed"` // stream amount released to date
+	Prepaid        string `json:"prepaid"`  // total stream amount in wei
+	Status         string `json:"status"`   // ok, underpaid, throttled or removed
+	UnderpaidSince int64  `json:"underpaidSince,omitempty"`
+	CreatedAt      int64  `json:"createdAt"`
+	UpdatedAt      int64  `json:"updatedAt"`
+
+	// Last counter values read from the interface, and the peer generation
+	// they belong to
+	CounterRx  int64  `json:"counterRx"`
+	CounterTx  int64  `json:"counterTx"`
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// ChainEvent is a decoded contract event stored by the indexer
+type ChainEvent struct {
+	Contract    string                 `json:"contract"`
+	Name        string                 `json:"name"`
+	BlockNumber uint64                 `json:"blockNumber"`
+	BlockHash   string                 `json:"blockHash"`
+	TxHash      string                 `json:"txHash"`
+	LogIndex    uint                   `json:"logIndex"`
+	Removed     bool                   `json:"removed"` // rolled back by a chain reorg
+	Data        map[string]interface{} `json:"data"`
+}
+
+// NodeAlert reports a NodeRegistry event affecting this node
+type NodeAlert struct {
+	Event         string `json:"event"`
+	Node          string `json:"node"`
+	SlashedAmount string `json:"slashedAmount,omitempty"`
+	NewReputation string `json:"newReputation,omitempty"`
+	BlockNumber   uint64 `json:"blockNumber"`
+	TxHash        string `json:"txHash"`
+	Removed       bool   `json:"removed"` // the event was rolled back by a chain reorg
+}
+
 // APIResponse represents a standard API response
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -99,4 +474,15 @@ type NodeStatus struct {
 	Uptime         time.Duration    `json:"uptime"`
 	Peers          map[string]*Peer `json:"peers"`
 }
- 
\ No newline at end of file
+
+// RPCEndpointStatus represents the health of an RPC endpoint
+type RPCEndpointStatus struct {
+	URL         string  `json:"url"`
+	Healthy     bool    `json:"healthy"`
+	BlockNumber uint64  `json:"blockNumber"`
+	BlockLag    uint64  `json:"blockLag"`
+	LatencyMs   int64   `json:"latencyMs"`
+	ErrorRate   float64 `json:"errorRate"`
+	LastError   string  `json:"lastError,omitempty"`
+	LastChecked int64   `json:"lastChecked"`
+}
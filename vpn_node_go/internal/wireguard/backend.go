@@ -0,0 +1,110 @@
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Backends selectable with WG_BACKEND
+const (
+	// BackendAuto uses the kernel module and falls back to userspace when it
+	// is missing
+	BackendAuto = "auto"
+
+	// BackendKernel is the WireGuard kernel module driven through wgctrl
+	BackendKernel = "kernel"
+
+	// BackendUserspace is an embedded wireguard-go device on a TUN interface
+	BackendUserspace = "userspace"
+)
+
+// Backend runs the WireGuard interface. Constructing a backend brings the
+// interface up; Close tears down what it set up.
+type Backend interface {
+	// Name returns the backend kind, BackendKernel or BackendUserspace
+	Name() string
+
+	// Device returns the configuration and peers of the named interface
+	Device(name string) (*wgtypes.Device, error)
+
+	// ConfigureDevice applies config to the named interface
+	ConfigureDevice(name string, config wgtypes.Config) error
+
+	// Close tears down the interface and releases the backend
+	Close() error
+}
+
+// NewBackend brings up the interface with the backend named by WG_BACKEND
+func NewBackend(config *types.NodeConfig, logger *logrus.Logger) (Backend, error) {
+	switch config.WGBackend {
+	case BackendKernel:
+		return newKernelBackend(config, logger)
+	case BackendUserspace:
+		return newUserspaceBackend(config, logger)
+	case BackendAuto, "":
+		backend, err := newKernelBackend(config, logger)
+		if errors.Is(err, ErrKernelModuleMissing) {
+			logger.Warnf("%v; falling back to userspace WireGuard", err)
+			return newUserspaceBackend(config, logger)
+		}
+		return backend, err
+	}
+	return nil, fmt.Errorf("invalid WG_BACKEND: %s", config.WGBackend)
+}
+
+// kernelBackend drives the WireGuard kernel module
+type kernelBackend struct {
+	config *types.NodeConfig
+	logger *logrus.Logger
+	client *wgctrl.Client
+	link   *linkState
+}
+
+// newKernelBackend creates or attaches to the kernel interface
+func newKernelBackend(config *types.NodeConfig, logger *logrus.Logger) (Backend, error) {
+	client, err := wgctrl.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wgctrl client: %w", err)
+	}
+
+	b := &kernelBackend{
+		config: config,
+		logger: logger,
+		client: client,
+	}
+	if err := b.setupLink(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Name returns BackendKernel
+func (b *kernelBackend) Name() string {
+	return BackendKernel
+}
+
+// Device returns the configuration and peers of the named interface
+func (b *kernelBackend) Device(name string) (*wgtypes.Device, error) {
+	return b.client.Device(name)
+}
+
+// ConfigureDevice applies config to the named interface
+func (b *kernelBackend) ConfigureDevice(name string, config wgtypes.Config) error {
+	return b.client.ConfigureDevice(name, config)
+}
+
+// Close tears down the link and closes the wgctrl client
+func (b *kernelBackend) Close() error {
+	if err := b.teardownLink(); err != nil {
+		b.logger.Errorf("Failed to tear down interface %s: %v", b.config.WGInterface, err)
+	}
+	return b.client.Close()
+}
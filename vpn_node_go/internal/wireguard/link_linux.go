@@ -0,0 +1,194 @@
+//go:build linux
+
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// linkState records what the node configured on the interface so that
+// Close can undo exactly that
+type linkState struct {
+	created bool            // the node created the link and deletes it whole
+	address *netlink.Addr   // server address added to an existing link
+	routes  []netlink.Route // routes added to an existing link
+}
+
+// setupLink creates the WireGuard link if it does not exist and configures
+// it. An existing WireGuard link is brought to the same state.
+func (b *kernelBackend) setupLink() error {
+	state := &linkState{}
+	link, err := netlink.LinkByName(b.config.WGInterface)
+	var notFound netlink.LinkNotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		b.logger.Infof("Creating WireGuard interface: %s", b.config.WGInterface)
+		link, err = b.createLink()
+		if err != nil {
+			return err
+		}
+		state.created = true
+	case err != nil:
+		return fmt.Errorf("failed to look up interface %s: %w", b.config.WGInterface, err)
+	case link.Type() != "wireguard":
+		return fmt.Errorf("interface %s exists but is a %s link, not wireguard", b.config.WGInterface, link.Type())
+	}
+
+	// From here on a failure leaves behind whatever was set up so far
+	b.link = state
+	if err := configureLink(b.config, b.logger, link, state); err != nil {
+		if teardownErr := b.teardownLink(); teardownErr != nil {
+			b.logger.Errorf("Failed to clean up interface %s: %v", b.config.WGInterface, teardownErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// setupTunLink configures the TUN link of a userspace device. The link goes
+// away with the device, so nothing needs to be undone.
+func setupTunLink(config *types.NodeConfig, logger *logrus.Logger) error {
+	link, err := netlink.LinkByName(config.WGInterface)
+	if err != nil {
+		return fmt.Errorf("failed to look up interface %s: %w", config.WGInterface, err)
+	}
+	return configureLink(config, logger, link, &linkState{created: true})
+}
+
+// configureLink assigns the server address from WG_SUBNET, sets the MTU,
+// brings the link up and routes WG_ROUTES through it. What it adds to a link
+// the node did not create is recorded in state.
+func configureLink(config *types.NodeConfig, logger *logrus.Logger, link netlink.Link, state *linkState) error {
+	address, err := netlink.ParseAddr(config.WGSubnet)
+	if err != nil {
+		return fmt.Errorf("invalid WG_SUBNET %s: %w", config.WGSubnet, err)
+	}
+
+	routes := make([]netlink.Route, 0, len(config.WGRoutes))
+	for _, cidr := range config.WGRoutes {
+		dst, err := netlink.ParseIPNet(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid WG_ROUTES entry %s: %w", cidr, err)
+		}
+		routes = append(routes, netlink.Route{Dst: dst, Scope: netlink.SCOPE_LINK})
+	}
+
+	hasAddress, err := linkHasAddress(link, address)
+	if err != nil {
+		return err
+	}
+	if !hasAddress {
+		if err := netlink.AddrAdd(link, address); err != nil {
+			return fmt.Errorf("failed to assign %s to %s: %w", address.IPNet, config.WGInterface, err)
+		}
+		if !state.created {
+			state.address = address
+		}
+	}
+
+	if config.WGMTU > 0 && link.Attrs().MTU != config.WGMTU {
+		if err := netlink.LinkSetMTU(link, config.WGMTU); err != nil {
+			return fmt.Errorf("failed to set MTU of %s to %d: %w", config.WGInterface, config.WGMTU, err)
+		}
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("failed to bring up %s: %w", config.WGInterface, err)
+	}
+
+	for _, route := range routes {
+		route.LinkIndex = link.Attrs().Index
+		if err := netlink.RouteReplace(&route); err != nil {
+			return fmt.Errorf("failed to route %s via %s: %w", route.Dst, config.WGInterface, err)
+		}
+		if !state.created {
+			state.routes = append(state.routes, route)
+		}
+	}
+
+	logger.Infof("Interface %s is up with address %s", config.WGInterface, address.IPNet)
+	return nil
+}
+
+// createLink adds a WireGuard link named WG_INTERFACE
+func (b *kernelBackend) createLink() (netlink.Link, error) {
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = b.config.WGInterface
+
+	if err := netlink.LinkAdd(&netlink.Wireguard{LinkAttrs: attrs}); err != nil {
+		switch {
+		case errors.Is(err, unix.EOPNOTSUPP):
+			return nil, ErrKernelModuleMissing
+		case errors.Is(err, unix.EPERM):
+			return nil, fmt.Errorf("failed to create interface %s: %w (CAP_NET_ADMIN is required)", b.config.WGInterface, err)
+		}
+		return nil, fmt.Errorf("failed to create interface %s: %w", b.config.WGInterface, err)
+	}
+
+	link, err := netlink.LinkByName(b.config.WGInterface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up created interface %s: %w", b.config.WGInterface, err)
+	}
+	return link, nil
+}
+
+// teardownLink deletes the link if the node created it, or removes the
+// address and routes it added to an existing one
+func (b *kernelBackend) teardownLink() error {
+	state := b.link
+	if state == nil {
+		return nil
+	}
+	b.link = nil
+
+	link, err := netlink.LinkByName(b.config.WGInterface)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to look up interface %s: %w", b.config.WGInterface, err)
+	}
+
+	if state.created {
+		if err := netlink.LinkDel(link); err != nil {
+			return fmt.Errorf("failed to delete interface %s: %w", b.config.WGInterface, err)
+		}
+		b.logger.Infof("Deleted WireGuard interface: %s", b.config.WGInterface)
+		return nil
+	}
+
+	var errs []error
+	for _, route := range state.routes {
+		if err := netlink.RouteDel(&route); err != nil && !errors.Is(err, unix.ESRCH) {
+			errs = append(errs, fmt.Errorf("failed to delete route %s: %w", route.Dst, err))
+		}
+	}
+	if state.address != nil {
+		if err := netlink.AddrDel(link, state.address); err != nil && !errors.Is(err, unix.EADDRNOTAVAIL) {
+			errs = append(errs, fmt.Errorf("failed to remove address %s: %w", state.address.IPNet, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// linkHasAddress reports whether address is already assigned to link
+func linkHasAddress(link netlink.Link, address *netlink.Addr) (bool, error) {
+	addresses, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return false, fmt.Errorf("failed to list addresses of %s: %w", link.Attrs().Name, err)
+	}
+	for _, existing := range addresses {
+		if existing.Equal(*address) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
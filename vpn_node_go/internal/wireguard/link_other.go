@@ -0,0 +1,68 @@
+//go:build !linux
+
+package wireguard
+
+import (
+	"fmt"
+	"os/exec"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// linkState is unused where the node cannot manage links itself
+type linkState struct{}
+
+// setupLink finds the WireGuard interface, or brings it up with wg-quick.
+// Without netlink the address, MTU and routes come from the wg-quick config.
+func (b *kernelBackend) setupLink() error {
+	if _, err := b.client.Device(b.config.WGInterface); err == nil {
+		return nil
+	}
+
+	// Try to find utun interface on macOS
+	if isMacOS() {
+		b.logger.Info("macOS detected, checking for utun interface...")
+		// On macOS, WireGuard interfaces are named utunX
+		for i := 0; i < 10; i++ {
+			utunName := fmt.Sprintf("utun%d", i)
+			if _, err := b.client.Device(utunName); err == nil {
+				b.logger.Infof("Found existing WireGuard interface: %s", utunName)
+				b.config.WGInterface = utunName
+				return nil
+			}
+		}
+	}
+
+	b.logger.Infof("No existing interface found, creating: %s", b.config.WGInterface)
+	if err := b.createInterface(); err != nil {
+		return fmt.Errorf("failed to create interface: %w", err)
+	}
+	return nil
+}
+
+// createInterface creates the WireGuard interface
+func (b *kernelBackend) createInterface() error {
+	b.logger.Infof("Creating WireGuard interface: %s", b.config.WGInterface)
+
+	// Use wg-quick to create interface (simplified)
+	cmd := exec.Command("wg-quick", "up", b.config.WGInterface)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create interface: %w", err)
+	}
+
+	return nil
+}
+
+// teardownLink leaves the interface to wg-quick
+func (b *kernelBackend) teardownLink() error {
+	return nil
+}
+
+// setupTunLink leaves the address, MTU and routes of a userspace device to
+// the operator
+func setupTunLink(config *types.NodeConfig, logger *logrus.Logger) error {
+	logger.Warnf("Configure the address and routes of %s manually, e.g. with ifconfig", config.WGInterface)
+	return nil
+}
@@ -0,0 +1,393 @@
+package wireguard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"dvpn-node/internal/types"
+)
+
+// Quota actions
+const (
+	QuotaWarn       = "warn"
+	QuotaThrottle   = "throttle"
+	QuotaDisconnect = "disconnect"
+)
+
+// Quota limits
+const (
+	LimitSession = "session"
+	LimitDaily   = "daily"
+	LimitPrepaid = "prepaid"
+	LimitNode    = "node"
+)
+
+const (
+	quotaBucket  = "quota"
+	nodeUsageKey = "node"
+
+	// dayFormat keys daily usage by UTC day
+	dayFormat = "2006-01-02"
+)
+
+// ErrNodeQuotaExceeded is returned by AddPeer once the node-wide daily data
+// cap has been reached
+var ErrNodeQuotaExceeded = errors.New("node daily data cap reached")
+
+// Throttler limits the bandwidth of a peer. Each source lifts only its own
+// throttle.
+type Throttler interface {
+	Throttle(publicKey, source string, bytesPerSecond uint64) error
+	Unthrottle(publicKey, source string) error
+}
+
+// PrepaidQuota reports how many bytes a peer has paid for in advance
+type PrepaidQuota interface {
+	PrepaidBytes(publicKey string) (int64, bool)
+}
+
+// quotaEvent is a quota action to carry out after the peers lock is released
+type quotaEvent struct {
+	publicKey string
+	limit     string
+	clear     bool // the limit was lifted, e.g. at the start of a new day
+}
+
+// SetThrottler sets the bandwidth limiter used by the throttle action
+func (w *WireGuardService) SetThrottler(throttler Throttler) {
+	w.throttler = throttler
+}
+
+// SetPrepaidQuota sets the source of prepaid data quotas
+func (w *WireGuardService) SetPrepaidQuota(prepaid PrepaidQuota) {
+	w.prepaid = prepaid
+}
+
+// Quota returns the limits, usage and remaining data of a peer
+func (w *WireGuardService) Quota(publicKey string) (*types.PeerQuota, bool) {
+	w.peersMutex.RLock()
+	usage, exists := w.usage[publicKey]
+	var snapshot types.PeerUsage
+	if exists {
+		snapshot = *usage
+	}
+	w.peersMutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	quota := &types.PeerQuota{
+		Enabled:  w.config.QuotaEnabled,
+		Exceeded: snapshot.Exceeded,
+	}
+	quota.Session = quotaLimit(w.config.QuotaSessionBytes, snapshot.SessionBytes)
+	if snapshot.Day == today() {
+		quota.Daily = quotaLimit(w.config.QuotaDailyBytes, snapshot.DailyBytes)
+	} else {
+		quota.Daily = quotaLimit(w.config.QuotaDailyBytes, 0)
+	}
+	if w.prepaid != nil {
+		if prepaid, ok := w.prepaid.PrepaidBytes(publicKey); ok {
+			quota.Prepaid = quotaLimit(prepaid, snapshot.SessionBytes)
+		}
+	}
+
+	for _, limit := range []*types.QuotaLimit{quota.Session, quota.Daily, quota.Prepaid} {
+		if limit != nil && (quota.Remaining == nil || limit.Remaining < *quota.Remaining) {
+			remaining := limit.Remaining
+			quota.Remaining = &remaining
+		}
+	}
+	return quota, true
+}
+
+// Usage returns the metered usage of a peer. SessionBytes covers the time
+// since the peer was added, across re-creations of the peer on the device.
+func (w *WireGuardService) Usage(publicKey string) (*types.PeerUsage, bool) {
+	w.peersMutex.RLock()
+	defer w.peersMutex.RUnlock()
+
+	usage, exists := w.usage[publicKey]
+	if !exists {
+		return nil, false
+	}
+	snapshot := *usage
+	return &snapshot, true
+}
+
+// NodeUsage returns the node-wide data usage of the current day
+func (w *WireGuardService) NodeUsage() types.QuotaLimit {
+	w.peersMutex.RLock()
+	defer w.peersMutex.RUnlock()
+
+	used := int64(0)
+	if w.nodeUsage.Day == today() {
+		used = w.nodeUsage.Bytes
+	}
+	if limit := quotaLimit(w.config.NodeBandwidth, used); limit != nil {
+		return *limit
+	}
+	return types.QuotaLimit{Used: used}
+}
+
+// loadUsage restores peer and node usage from the store
+func (w *WireGuardService) loadUsage() error {
+	return w.store.ForEach(quotaBucket, func(key string, value []byte) error {
+		if key == nodeUsageKey {
+			return json.Unmarshal(value, &w.nodeUsage)
+		}
+		var usage types.PeerUsage
+		if err := json.Unmarshal(value, &usage); err != nil {
+			return err
+		}
+		w.usage[key] = &usage
+		return nil
+	})
+}
+
+// meter adds the traffic since the last stats cycle to the usage of peer. It
+// returns the traffic and the quota events it caused. generation is that of
+// the peer the counters were read from, and prepaid holds the prepaid quotas,
+// read before the lock was taken. Callers must hold peersMutex.
+func (w *WireGuardService) meter(publicKey string, generation uint64, rx, tx int64, day string, prepaid map[string]int64) (int64, []quotaEvent) {
+	usage, exists := w.usage[publicKey]
+	if !exists {
+		usage = &types.PeerUsage{Day: day, CounterRx: rx, CounterTx: tx, Generation: generation, SessionID: generation}
+		w.usage[publicKey] = usage
+	}
+
+	// Counters restart from zero only when the peer is re-created on the
+	// device. Any other drop is a stale reading and meters nothing.
+	if usage.Generation != generation {
+		usage.Generation = generation
+		usage.CounterRx, usage.CounterTx = 0, 0
+	}
+	deltaRx, deltaTx := max(rx-usage.CounterRx, 0), max(tx-usage.CounterTx, 0)
+	usage.CounterRx, usage.CounterTx = max(usage.CounterRx, rx), max(usage.CounterTx, tx)
+	delta := deltaRx + deltaTx
+
+	var events []quotaEvent
+	if usage.Day != day {
+		usage.Day = day
+		usage.DailyBytes = 0
+		if usage.Exceeded == LimitDaily {
+			usage.Exceeded = ""
+			events = append(events, quotaEvent{publicKey: publicKey, limit: LimitDaily, clear: true})
+		}
+	}
+	usage.SessionBytes += delta
+	usage.DailyBytes += delta
+
+	if !w.config.QuotaEnabled || usage.Exceeded != "" {
+		return delta, events
+	}
+
+	switch {
+	case exceeds(w.config.QuotaSessionBytes, usage.SessionBytes):
+		usage.Exceeded = LimitSession
+	case exceeds(w.config.QuotaDailyBytes, usage.DailyBytes):
+		usage.Exceeded = LimitDaily
+	case prepaidExceeded(prepaid, publicKey, usage.SessionBytes):
+		usage.Exceeded = LimitPrepaid
+	default:
+		return delta, events
+	}
+
+	return delta, append(events, quotaEvent{publicKey: publicKey, limit: usage.Exceeded})
+}
+
+// meterNode adds traffic to the node-wide usage and reports whether the
+// daily cap was crossed or lifted. Callers must hold peersMutex.
+func (w *WireGuardService) meterNode(delta int64, day string) (crossed, lifted bool) {
+	if w.nodeUsage.Day != day {
+		lifted = w.nodeUsage.Exceeded
+		w.nodeUsage = types.NodeUsage{Day: day}
+	}
+	w.nodeUsage.Bytes += delta
+
+	if w.config.QuotaEnabled && !w.nodeUsage.Exceeded && exceeds(w.config.NodeBandwidth, w.nodeUsage.Bytes) {
+		w.nodeUsage.Exceeded = true
+		crossed = true
+	}
+	return crossed, lifted
+}
+
+// saveUsage persists the usage of the given peers and the node
+func (w *WireGuardService) saveUsage(publicKeys []string) {
+	w.peersMutex.RLock()
+	node := w.nodeUsage
+	usages := make(map[string]types.PeerUsage, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		if usage, exists := w.usage[publicKey]; exists {
+			usages[publicKey] = *usage
+		}
+	}
+	w.peersMutex.RUnlock()
+
+	for publicKey, usage := range usages {
+		if err := w.store.Put(quotaBucket, publicKey, usage); err != nil {
+			w.logger.Errorf("Failed to persist usage of peer %s: %v", publicKey, err)
+		}
+	}
+	if err := w.store.Put(quotaBucket, nodeUsageKey, node); err != nil {
+		w.logger.Errorf("Failed to persist node usage: %v", err)
+	}
+}
+
+// forgetUsage drops the usage of a removed peer. Callers must hold peersMutex.
+func (w *WireGuardService) forgetUsage(publicKey string) {
+	delete(w.usage, publicKey)
+	if err := w.store.Delete(quotaBucket, publicKey); err != nil {
+		w.logger.Errorf("Failed to delete usage of peer %s: %v", publicKey, err)
+	}
+}
+
+// enforceQuotas carries out the configured action for every crossed limit
+func (w *WireGuardService) enforceQuotas(events []quotaEvent) {
+	for _, event := range events {
+		if event.clear {
+			w.liftQuota(event.publicKey, event.limit)
+			continue
+		}
+
+		w.logger.Warnf("Peer %s exceeded its %s quota, action: %s", event.publicKey, event.limit, w.config.QuotaAction)
+		w.notify(types.WebSocketMessage{
+			Type: "quota_exceeded",
+			Payload: map[string]interface{}{
+				"publicKey": event.publicKey,
+				"limit":     event.limit,
+				"action":    w.config.QuotaAction,
+			},
+		})
+
+		switch w.config.QuotaAction {
+		case QuotaThrottle:
+			if w.throttler != nil {
+				if err := w.throttler.Throttle(event.publicKey, throttleSource(event.limit), w.config.QuotaThrottleRate); err != nil {
+					w.logger.Errorf("Failed to throttle peer %s: %v", event.publicKey, err)
+				}
+				continue
+			}
+			w.logger.Warn("No bandwidth limiter available, disconnecting peer instead")
+			w.disconnect(event.publicKey)
+		case QuotaDisconnect:
+			w.disconnect(event.publicKey)
+		}
+	}
+}
+
+// liftQuota removes the throttle of a peer whose limit has been lifted
+func (w *WireGuardService) liftQuota(publicKey, limit string) {
+	w.logger.Infof("Peer %s %s quota reset", publicKey, limit)
+	if w.config.QuotaAction == QuotaThrottle && w.throttler != nil {
+		if err := w.throttler.Unthrottle(publicKey, throttleSource(limit)); err != nil {
+			w.logger.Errorf("Failed to unthrottle peer %s: %v", publicKey, err)
+		}
+	}
+}
+
+// throttleSource names the throttle put on a peer for crossing limit, so that
+// resetting one limit does not lift the throttle of another
+func throttleSource(limit string) string {
+	return "quota:" + limit
+}
+
+// enforceNodeQuota applies the quota action to every peer once the node-wide
+// daily cap is crossed, and lifts it when a new day starts
+func (w *WireGuardService) enforceNodeQuota(crossed, lifted bool) {
+	if lifted {
+		w.logger.Info("Node daily data cap reset")
+		for publicKey := range w.GetPeers() {
+			w.liftQuota(publicKey, LimitNode)
+		}
+	}
+	if !crossed {
+		return
+	}
+
+	var events []quotaEvent
+	for publicKey := range w.GetPeers() {
+		events = append(events, quotaEvent{publicKey: publicKey, limit: LimitNode})
+	}
+
+	w.logger.Warnf("Node reached its daily data cap of %d bytes", w.config.NodeBandwidth)
+	w.enforceQuotas(events)
+}
+
+// disconnect removes a peer that exceeded its quota
+func (w *WireGuardService) disconnect(publicKey string) {
+	if err := w.RemovePeer(publicKey); err != nil {
+		w.logger.Errorf("Failed to disconnect peer %s: %v", publicKey, err)
+		return
+	}
+
+	w.notify(types.WebSocketMessage{
+		Type: "peer_removed",
+		Payload: map[string]interface{}{
+			"publicKey": publicKey,
+			"reason":    "quota_exceeded",
+		},
+	})
+}
+
+// nodeQuotaExceeded reports whether new peers are refused by the node-wide cap
+func (w *WireGuardService) nodeQuotaExceeded() bool {
+	w.peersMutex.RLock()
+	defer w.peersMutex.RUnlock()
+
+	return w.config.QuotaEnabled && w.nodeUsage.Exceeded && w.nodeUsage.Day == today()
+}
+
+// prepaidQuotas returns the prepaid quota of every peer that has one
+func (w *WireGuardService) prepaidQuotas() map[string]int64 {
+	quotas := make(map[string]int64)
+	if w.prepaid == nil || !w.config.QuotaEnabled {
+		return quotas
+	}
+	for publicKey := range w.GetPeers() {
+		if prepaid, ok := w.prepaid.PrepaidBytes(publicKey); ok {
+			quotas[publicKey] = prepaid
+		}
+	}
+	return quotas
+}
+
+// prepaidExceeded reports whether a peer has used all the data it prepaid
+func prepaidExceeded(prepaid map[string]int64, publicKey string, used int64) bool {
+	limit, ok := prepaid[publicKey]
+	return ok && used >= limit
+}
+
+// quotaLimit returns the usage against limit, or nil when limit is disabled
+func quotaLimit(limit, used int64) *types.QuotaLimit {
+	if limit <= 0 {
+		return nil
+	}
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &types.QuotaLimit{Limit: limit, Used: used, Remaining: remaining}
+}
+
+// exceeds reports whether used has reached a limit; limits <= 0 are disabled
+func exceeds(limit, used int64) bool {
+	return limit > 0 && used >= limit
+}
+
+// today returns the current UTC day
+func today() string {
+	return time.Now().UTC().Format(dayFormat)
+}
+
+// validQuotaAction reports whether action is a known quota action
+func validQuotaAction(action string) error {
+	switch action {
+	case QuotaWarn, QuotaThrottle, QuotaDisconnect:
+		return nil
+	}
+	return fmt.Errorf("invalid QUOTA_ACTION: %s", action)
+}
@@ -0,0 +1,105 @@
+package wireguard
+
+import (
+	"context"
+	"time"
+
+	"dvpn-node/internal/types"
+)
+
+// Reasons the reaper removes a peer
+const (
+	ReapIdle     = "idle"
+	ReapLifetime = "lifetime_exceeded"
+)
+
+// ExpiryChecker reports whether the session or payment behind a peer has
+// expired, and why
+type ExpiryChecker interface {
+	Expired(publicKey string) (reason string, expired bool)
+}
+
+// SetExpiryChecker sets the check the reaper uses to find peers whose
+// session or payment has expired
+func (w *WireGuardService) SetExpiryChecker(checker ExpiryChecker) {
+	w.expiry = checker
+}
+
+// RunReaper removes dead peers every WG_REAP_INTERVAL until ctx is
+// cancelled: peers without a handshake for WG_REAP_IDLE_TIMEOUT (counted
+// from when they were added if they never connected), peers older than
+// WG_MAX_PEER_LIFETIME, and peers whose session or payment has expired.
+func (w *WireGuardService) RunReaper(ctx context.Context) {
+	ticker := time.NewTicker(w.config.WGReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reap()
+		}
+	}
+}
+
+// reap removes every peer that is due for removal
+func (w *WireGuardService) reap() {
+	statsFresh := true
+	if err := w.UpdatePeerStats(); err != nil {
+		// Handshake times are unknown, so only lifetime and expiry apply
+		w.logger.Warnf("Failed to update peer stats before reaping: %v", err)
+		statsFresh = false
+	}
+
+	now := time.Now()
+	for publicKey, peer := range w.GetPeers() {
+		reason, due := w.reapReason(peer, now, statsFresh)
+		if !due {
+			continue
+		}
+
+		if err := w.RemovePeer(publicKey); err != nil {
+			w.logger.Errorf("Failed to reap peer %s (%s): %v", publicKey, reason, err)
+			continue
+		}
+
+		w.logger.Infof("Reaped peer %s: %s", publicKey, reason)
+		w.notify(types.WebSocketMessage{
+			Type: "peer_removed",
+			Payload: map[string]interface{}{
+				"publicKey": publicKey,
+				"reason":    reason,
+			},
+		})
+	}
+}
+
+// reapReason returns why peer should be removed at now, if it should. The
+// idle timeout only applies when statsFresh reports that handshake times were
+// just read from the device.
+func (w *WireGuardService) reapReason(peer *types.Peer, now time.Time, statsFresh bool) (string, bool) {
+	lastHandshake, createdAt := peer.LastHandshake, peer.CreatedAt
+
+	if w.expiry != nil {
+		if reason, expired := w.expiry.Expired(peer.PublicKey); expired {
+			return reason, true
+		}
+	}
+
+	if lifetime := w.config.WGMaxPeerLifetime; lifetime > 0 && !createdAt.IsZero() && now.Sub(createdAt) >= lifetime {
+		return ReapLifetime, true
+	}
+
+	if idle := w.config.WGReapIdleTimeout; idle > 0 && statsFresh {
+		since := lastHandshake
+		if since.IsZero() {
+			since = createdAt
+		}
+		if !since.IsZero() && now.Sub(since) >= idle {
+			return ReapIdle, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,249 @@
+package wireguard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Reconcile brings the device, the peer store and open sessions back in
+// sync, typically after a restart. Stored and session peers missing from the
+// device, or configured with different allowed IPs, are re-applied. Device
+// peers the node does not know about are adopted into the store when
+// WG_ADOPT_ORPHANS is set and removed otherwise. Address leases without a
+// peer are released, and every remaining peer is re-shaped. sessions maps
+// the public key of each open session to its tunnel addresses.
+func (w *WireGuardService) Reconcile(sessions map[string][]string) (*types.ReconcileReport, error) {
+	report := &types.ReconcileReport{Timestamp: time.Now().Unix()}
+
+	device, err := w.device.Device(w.config.WGInterface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	stored, err := w.storedPeers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored peers: %w", err)
+	}
+
+	// Sessions are authoritative for their addresses
+	for publicKey, addresses := range sessions {
+		peer, exists := stored[publicKey]
+		if !exists {
+			peer = &types.Peer{PublicKey: publicKey, CreatedAt: time.Now()}
+			stored[publicKey] = peer
+		}
+		peer.AllowedIPs = addresses
+	}
+
+	live := make(map[string]wgtypes.Peer, len(device.Peers))
+	for _, peer := range device.Peers {
+		live[peer.PublicKey.String()] = peer
+	}
+
+	// Device peers unknown to the store and sessions
+	for publicKey, devicePeer := range live {
+		if _, known := stored[publicKey]; known {
+			continue
+		}
+
+		if w.config.WGAdoptOrphans {
+			allowedIPs := make([]string, 0, len(devicePeer.AllowedIPs))
+			for _, ipNet := range devicePeer.AllowedIPs {
+				allowedIPs = append(allowedIPs, ipNet.String())
+			}
+
+			allowedIPs, err := w.ipam.Reserve(publicKey, allowedIPs)
+			if err == nil {
+				stored[publicKey] = &types.Peer{PublicKey: publicKey, AllowedIPs: allowedIPs, CreatedAt: time.Now()}
+				report.Adopted = append(report.Adopted, publicKey)
+				continue
+			}
+			w.logger.Warnf("Cannot adopt peer %s, removing it: %v", publicKey, err)
+		}
+
+		if err := w.removeDevicePeer(devicePeer.PublicKey); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("remove %s: %v", publicKey, err))
+			continue
+		}
+		report.Removed = append(report.Removed, publicKey)
+	}
+
+	// Stored and session peers missing from the device or out of date
+	for publicKey, peer := range stored {
+		if devicePeer, exists := live[publicKey]; exists && sameIPs(devicePeer.AllowedIPs, peer.AllowedIPs) {
+			if _, err := w.ipam.Reserve(publicKey, peer.AllowedIPs); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("lease %s: %v", publicKey, err))
+			}
+			continue
+		}
+
+		_, onDevice := live[publicKey]
+		if err := w.applyPeer(peer); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("apply %s: %v", publicKey, err))
+			delete(stored, publicKey)
+			continue
+		}
+
+		if onDevice {
+			report.Updated = append(report.Updated, publicKey)
+		} else {
+			w.recreated(peer)
+			report.Restored = append(report.Restored, publicKey)
+		}
+	}
+
+	// Persist the reconciled peers and rebuild the in-memory view
+	now := time.Now()
+	peers := make(map[string]*types.Peer, len(stored))
+	for publicKey, peer := range stored {
+		peer.State = ""
+		if devicePeer, exists := live[publicKey]; exists {
+			w.observe(peer, &devicePeer, now)
+		} else {
+			peer.State = PeerPending
+			peer.IsActive = false
+		}
+
+		if err := w.store.Put(peersBucket, publicKey, peer); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("store %s: %v", publicKey, err))
+		}
+		peers[publicKey] = peer
+	}
+
+	for _, lease := range w.ipam.Leases() {
+		if _, exists := peers[lease.PublicKey]; exists {
+			continue
+		}
+		if err := w.ipam.Release(lease.PublicKey); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("release %s: %v", lease.PublicKey, err))
+			continue
+		}
+		report.Released = append(report.Released, lease.PublicKey)
+	}
+
+	for _, list := range [][]string{report.Restored, report.Updated, report.Adopted, report.Removed, report.Released} {
+		sort.Strings(list)
+	}
+
+	w.peersMutex.Lock()
+	previous := w.peers
+	w.peers = peers
+	w.lastReconcile = report
+	w.peersMutex.Unlock()
+
+	for publicKey := range previous {
+		if _, exists := peers[publicKey]; !exists {
+			w.unshape(publicKey)
+		}
+	}
+	for publicKey, peer := range peers {
+		w.shape(publicKey, peer.AllowedIPs)
+	}
+
+	w.logger.Infof("Reconciled %d peers: %d restored, %d updated, %d adopted, %d removed, %d leases released, %d errors",
+		len(peers), len(report.Restored), len(report.Updated), len(report.Adopted), len(report.Removed), len(report.Released), len(report.Errors))
+	for _, message := range report.Errors {
+		w.logger.Warnf("Reconcile error: %s", message)
+	}
+
+	return report, nil
+}
+
+// LastReconcile returns the report of the last reconciliation
+func (w *WireGuardService) LastReconcile() *types.ReconcileReport {
+	w.peersMutex.RLock()
+	defer w.peersMutex.RUnlock()
+
+	return w.lastReconcile
+}
+
+// applyPeer leases the addresses of peer and configures it on the device
+func (w *WireGuardService) applyPeer(peer *types.Peer) error {
+	peerKey, err := wgtypes.ParseKey(peer.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	allowedIPs, err := w.ipam.Reserve(peer.PublicKey, peer.AllowedIPs)
+	if err != nil {
+		return err
+	}
+	peer.AllowedIPs = allowedIPs
+
+	var ipNets []net.IPNet
+	for _, ipStr := range allowedIPs {
+		_, ipNet, err := net.ParseCIDR(ipStr)
+		if err != nil {
+			return fmt.Errorf("invalid IP address: %s", ipStr)
+		}
+		ipNets = append(ipNets, *ipNet)
+	}
+
+	config := wgtypes.Config{
+		Peers: []wgtypes.PeerConfig{
+			{
+				PublicKey:         peerKey,
+				ReplaceAllowedIPs: true,
+				AllowedIPs:        ipNets,
+			},
+		},
+	}
+
+	if err := w.device.ConfigureDevice(w.config.WGInterface, config); err != nil {
+		return fmt.Errorf("failed to configure peer: %w", err)
+	}
+	return nil
+}
+
+// removeDevicePeer removes a peer from the device only
+func (w *WireGuardService) removeDevicePeer(peerKey wgtypes.Key) error {
+	config := wgtypes.Config{
+		Peers: []wgtypes.PeerConfig{
+			{
+				PublicKey: peerKey,
+				Remove:    true,
+			},
+		},
+	}
+	return w.device.ConfigureDevice(w.config.WGInterface, config)
+}
+
+// storedPeers returns the peers in the store by public key
+func (w *WireGuardService) storedPeers() (map[string]*types.Peer, error) {
+	peers := make(map[string]*types.Peer)
+	err := w.store.ForEach(peersBucket, func(key string, value []byte) error {
+		var peer types.Peer
+		if err := json.Unmarshal(value, &peer); err != nil {
+			return err
+		}
+		peers[key] = &peer
+		return nil
+	})
+	return peers, err
+}
+
+// sameIPs reports whether a device peer routes exactly the given addresses
+func sameIPs(ipNets []net.IPNet, addresses []string) bool {
+	if len(ipNets) != len(addresses) {
+		return false
+	}
+
+	configured := make(map[string]bool, len(ipNets))
+	for _, ipNet := range ipNets {
+		configured[ipNet.String()] = true
+	}
+	for _, address := range addresses {
+		_, ipNet, err := net.ParseCIDR(address)
+		if err != nil || !configured[ipNet.String()] {
+			return false
+		}
+	}
+	return true
+}
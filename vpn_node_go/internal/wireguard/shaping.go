@@ -0,0 +1,34 @@
+package wireguard
+
+// TrafficShaper caps the bandwidth of peers by their tunnel addresses
+type TrafficShaper interface {
+	Apply(publicKey string, addresses []string) error
+	Remove(publicKey string) error
+}
+
+// SetShaper sets the traffic shaper that rate limits peers as they are
+// added, re-addressed and removed
+func (w *WireGuardService) SetShaper(shaper TrafficShaper) {
+	w.shaper = shaper
+}
+
+// shape applies the rate limit of a peer. A peer that cannot be shaped keeps
+// its connection; the failure is only logged.
+func (w *WireGuardService) shape(publicKey string, addresses []string) {
+	if w.shaper == nil {
+		return
+	}
+	if err := w.shaper.Apply(publicKey, addresses); err != nil {
+		w.logger.Errorf("Failed to shape peer %s: %v", publicKey, err)
+	}
+}
+
+// unshape removes the rate limit of a peer
+func (w *WireGuardService) unshape(publicKey string) {
+	if w.shaper == nil {
+		return
+	}
+	if err := w.shaper.Remove(publicKey); err != nil {
+		w.logger.Errorf("Failed to remove rate limit of peer %s: %v", publicKey, err)
+	}
+}
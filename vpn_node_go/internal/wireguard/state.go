@@ -0,0 +1,107 @@
+package wireguard
+
+import (
+	"time"
+
+	"dvpn-node/internal/types"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Peer connectivity states
+const (
+	// PeerPending is a peer that has never completed a handshake
+	PeerPending = "pending"
+
+	// PeerConnected is a peer with a recent handshake that is moving traffic
+	PeerConnected = "connected"
+
+	// PeerIdle is a peer whose session is still fresh but that has stopped
+	// moving traffic, or whose handshake is overdue
+	PeerIdle = "idle"
+
+	// PeerStale is a peer that has not completed a handshake for WG_STALE_TIMEOUT
+	PeerStale = "stale"
+)
+
+// stateChange is a peer state transition to report after the peers lock is released
+type stateChange struct {
+	publicKey     string
+	from          string
+	to            string
+	lastHandshake time.Time
+	lastActivity  time.Time
+}
+
+// SetNotifier sets the function used to report peer state transitions to WebSocket clients
+func (w *WireGuardService) SetNotifier(notify func(types.WebSocketMessage)) {
+	w.notify = notify
+}
+
+// observe updates the counters and state of peer from the device and returns
+// the state transition, if any. Activity is when the byte counters last moved;
+// WireGuard re-handshakes every two minutes while traffic flows, so a
+// handshake older than WG_HANDSHAKE_TIMEOUT means the tunnel has gone quiet.
+func (w *WireGuardService) observe(peer *types.Peer, device *wgtypes.Peer, now time.Time) (stateChange, bool) {
+	if device.ReceiveBytes != peer.BytesRx || device.TransmitBytes != peer.BytesTx {
+		peer.LastActivity = now
+	}
+	peer.BytesRx = device.ReceiveBytes
+	peer.BytesTx = device.TransmitBytes
+	if device.Endpoint != nil {
+		peer.Endpoint = device.Endpoint.String()
+	}
+	if !device.LastHandshakeTime.IsZero() {
+		peer.LastHandshake = device.LastHandshakeTime
+		peer.LastSeen = device.LastHandshakeTime
+	}
+
+	from := peer.State
+	peer.State = w.classify(peer, now)
+	peer.IsActive = peer.State == PeerConnected
+
+	change := stateChange{
+		publicKey:     peer.PublicKey,
+		from:          from,
+		to:            peer.State,
+		lastHandshake: peer.LastHandshake,
+		lastActivity:  peer.LastActivity,
+	}
+	// A peer seen for the first time has no previous state to leave
+	return change, from != "" && from != peer.State
+}
+
+// classify derives the connectivity state of peer at now
+func (w *WireGuardService) classify(peer *types.Peer, now time.Time) string {
+	if peer.LastHandshake.IsZero() {
+		return PeerPending
+	}
+
+	sinceHandshake := now.Sub(peer.LastHandshake)
+	switch {
+	case sinceHandshake >= w.config.WGStaleTimeout:
+		return PeerStale
+	case sinceHandshake >= w.config.WGHandshakeTimeout:
+		return PeerIdle
+	case now.Sub(peer.LastActivity) >= w.config.WGIdleTimeout:
+		return PeerIdle
+	}
+	return PeerConnected
+}
+
+// notifyChanges pushes peer state transitions to WebSocket clients
+func (w *WireGuardService) notifyChanges(changes []stateChange) {
+	for _, change := range changes {
+		w.logger.Debugf("Peer %s is now %s (was %s)", change.publicKey, change.to, change.from)
+		w.notify(types.WebSocketMessage{
+			Type: "peer_state",
+			Payload: map[string]interface{}{
+				"publicKey":     change.publicKey,
+				"from":          change.from,
+				"to":            change.to,
+				"lastHandshake": change.lastHandshake,
+				"lastActivity":  change.lastActivity,
+			},
+		})
+	}
+}
@@ -0,0 +1,134 @@
+//go:build linux || darwin || freebsd || openbsd
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/tun"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// userspaceBackend runs an embedded wireguard-go device on a TUN interface.
+// The device serves the standard UAPI socket, so it is configured through
+// wgctrl like the kernel module and can be inspected with the wg tool.
+type userspaceBackend struct {
+	config *types.NodeConfig
+	logger *logrus.Logger
+	device *device.Device
+	uapi   net.Listener
+	client *wgctrl.Client
+}
+
+// newUserspaceBackend creates the TUN interface and starts the device on it
+func newUserspaceBackend(config *types.NodeConfig, logger *logrus.Logger) (Backend, error) {
+	mtu := config.WGMTU
+	if mtu <= 0 {
+		mtu = device.DefaultMTU
+	}
+
+	tunDevice, err := tun.CreateTUN(config.WGInterface, mtu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN interface %s (is /dev/net/tun available?): %w", config.WGInterface, err)
+	}
+
+	// The kernel may pick the name, e.g. utunN on macOS
+	if name, err := tunDevice.Name(); err == nil && name != config.WGInterface {
+		logger.Infof("TUN interface is named %s instead of %s", name, config.WGInterface)
+		config.WGInterface = name
+	}
+
+	b := &userspaceBackend{
+		config: config,
+		logger: logger,
+		device: device.NewDevice(tunDevice, conn.NewDefaultBind(), &device.Logger{
+			Verbosef: logger.Debugf,
+			Errorf:   logger.Errorf,
+		}),
+	}
+
+	if err := b.start(); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	logger.Infof("Userspace WireGuard device running on %s", config.WGInterface)
+	return b, nil
+}
+
+// start opens the UAPI socket, configures the TUN link and brings the device up
+func (b *userspaceBackend) start() error {
+	file, err := ipc.UAPIOpen(b.config.WGInterface)
+	if err != nil {
+		return fmt.Errorf("failed to open UAPI socket: %w", err)
+	}
+
+	b.uapi, err = ipc.UAPIListen(b.config.WGInterface, file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to listen on UAPI socket: %w", err)
+	}
+	go b.serveUAPI()
+
+	b.client, err = wgctrl.New()
+	if err != nil {
+		return fmt.Errorf("failed to create wgctrl client: %w", err)
+	}
+
+	if err := setupTunLink(b.config, b.logger); err != nil {
+		return err
+	}
+
+	if err := b.device.Up(); err != nil {
+		return fmt.Errorf("failed to bring up device: %w", err)
+	}
+	return nil
+}
+
+// serveUAPI hands UAPI connections to the device until the socket is closed
+func (b *userspaceBackend) serveUAPI() {
+	for {
+		conn, err := b.uapi.Accept()
+		if err != nil {
+			return
+		}
+		go b.device.IpcHandle(conn)
+	}
+}
+
+// Name returns BackendUserspace
+func (b *userspaceBackend) Name() string {
+	return BackendUserspace
+}
+
+// Device returns the configuration and peers of the named interface
+func (b *userspaceBackend) Device(name string) (*wgtypes.Device, error) {
+	return b.client.Device(name)
+}
+
+// ConfigureDevice applies config to the named interface
+func (b *userspaceBackend) ConfigureDevice(name string, config wgtypes.Config) error {
+	return b.client.ConfigureDevice(name, config)
+}
+
+// Close stops the device, which removes its TUN interface with the address
+// and routes on it
+func (b *userspaceBackend) Close() error {
+	if b.uapi != nil {
+		b.uapi.Close()
+	}
+	b.device.Close()
+
+	if b.client != nil {
+		return b.client.Close()
+	}
+	return nil
+}
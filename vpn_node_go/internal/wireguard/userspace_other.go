@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !openbsd
+
+package wireguard
+
+import (
+	"errors"
+
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newUserspaceBackend fails where the embedded device has no UAPI socket
+func newUserspaceBackend(config *types.NodeConfig, logger *logrus.Logger) (Backend, error) {
+	return nil, errors.New("userspace WireGuard is not supported on this platform")
+}
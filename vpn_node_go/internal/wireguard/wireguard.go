@@ -1,48 +1,90 @@
 package wireguard
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"os/exec"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"dvpn-node/internal/ipam"
+	"dvpn-node/internal/store"
 	"dvpn-node/internal/types"
 
 	"github.com/sirupsen/logrus"
-	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
+// peersBucket holds the configured peers so they survive restarts
+const peersBucket = "peers"
+
+// ErrNotAcceptingPeers is returned by AddPeer while the node is leaving the network
+var ErrNotAcceptingPeers = errors.New("node is not accepting new peers")
+
+// ErrKernelModuleMissing is returned when the kernel cannot create WireGuard links
+var ErrKernelModuleMissing = errors.New("WireGuard kernel module is not available; load it with 'modprobe wireguard' or use a kernel with WireGuard built in")
+
 // WireGuardService manages WireGuard interface and peers
 type WireGuardService struct {
-	config     *types.NodeConfig
-	logger     *logrus.Logger
-	device     *wgctrl.Client
-	peers      map[string]*types.Peer
-	peersMutex sync.RWMutex
-	startTime  time.Time
+	config         *types.NodeConfig
+	logger         *logrus.Logger
+	device         Backend
+	ipam           *ipam.Allocator
+	store          *store.Store
+	peers          map[string]*types.Peer
+	peersMutex     sync.RWMutex
+	statsMutex     sync.Mutex // serialises UpdatePeerStats
+	generation     atomic.Uint64
+	acceptingPeers bool
+	startTime      time.Time
+	lastReconcile  *types.ReconcileReport
+	notify         func(types.WebSocketMessage)
+	expiry         ExpiryChecker
+	throttler      Throttler
+	prepaid        PrepaidQuota
+	shaper         TrafficShaper
+	usage          map[string]*types.PeerUsage
+	nodeUsage      types.NodeUsage
 }
 
 // NewWireGuardService creates a new WireGuard service
-func NewWireGuardService(config *types.NodeConfig, logger *logrus.Logger) (*WireGuardService, error) {
-	device, err := wgctrl.New()
+func NewWireGuardService(config *types.NodeConfig, allocator *ipam.Allocator, db *store.Store, logger *logrus.Logger) (*WireGuardService, error) {
+	if config.QuotaEnabled {
+		if err := validQuotaAction(config.QuotaAction); err != nil {
+			return nil, err
+		}
+	}
+
+	// Bring up the interface
+	device, err := NewBackend(config, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create wgctrl client: %w", err)
+		return nil, fmt.Errorf("failed to initialize interface: %w", err)
 	}
 
 	service := &WireGuardService{
-		config:    config,
-		logger:    logger,
-		device:    device,
-		peers:     make(map[string]*types.Peer),
-		startTime: time.Now(),
+		config:         config,
+		logger:         logger,
+		device:         device,
+		ipam:           allocator,
+		store:          db,
+		peers:          make(map[string]*types.Peer),
+		acceptingPeers: true,
+		startTime:      time.Now(),
+		notify:         func(types.WebSocketMessage) {},
+		usage:          make(map[string]*types.PeerUsage),
+	}
+
+	if err := service.loadUsage(); err != nil {
+		device.Close()
+		return nil, fmt.Errorf("failed to load peer usage: %w", err)
 	}
 
 	// Initialize WireGuard interface
 	if err := service.initializeInterface(); err != nil {
+		device.Close()
 		return nil, fmt.Errorf("failed to initialize interface: %w", err)
 	}
 
@@ -53,63 +95,24 @@ func NewWireGuardService(config *types.NodeConfig, logger *logrus.Logger) (*Wire
 func (w *WireGuardService) initializeInterface() error {
 	w.logger.Info("Initializing WireGuard interface...")
 
-	// Check if interface exists
-	_, err := w.device.Device(w.config.WGInterface)
-	if err != nil {
-		// Try to find utun interface on macOS
-		if w.isMacOS() {
-			w.logger.Info("macOS detected, checking for utun interface...")
-			// On macOS, WireGuard interfaces are named utunX
-			for i := 0; i < 10; i++ {
-				utunName := fmt.Sprintf("utun%d", i)
-				if _, err := w.device.Device(utunName); err == nil {
-					w.logger.Infof("Found existing WireGuard interface: %s", utunName)
-					w.config.WGInterface = utunName
-					break
-				}
-			}
-		}
-
-		// If still no interface found, try to create one
-		if _, err := w.device.Device(w.config.WGInterface); err != nil {
-			w.logger.Infof("No existing interface found, creating: %s", w.config.WGInterface)
-			if err := w.createInterface(); err != nil {
-				return fmt.Errorf("failed to create interface: %w", err)
-			}
-		}
-	}
-
 	// Try to configure the interface (skip if it fails on macOS)
 	if err := w.configureInterface(); err != nil {
-		if w.isMacOS() {
+		if isMacOS() {
 			w.logger.Warn("Skipping interface configuration on macOS (interface may already be configured)")
 		} else {
 			return fmt.Errorf("failed to configure interface: %w", err)
 		}
 	}
 
-	w.logger.Info("WireGuard interface initialized successfully")
+	w.logger.Infof("WireGuard interface initialized successfully (%s backend)", w.device.Name())
 	return nil
 }
 
 // isMacOS checks if running on macOS
-func (w *WireGuardService) isMacOS() bool {
+func isMacOS() bool {
 	return strings.Contains(strings.ToLower(runtime.GOOS), "darwin")
 }
 
-// createInterface creates the WireGuard interface
-func (w *WireGuardService) createInterface() error {
-	w.logger.Infof("Creating WireGuard interface: %s", w.config.WGInterface)
-
-	// Use wg-quick to create interface (simplified)
-	cmd := exec.Command("wg-quick", "up", w.config.WGInterface)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create interface: %w", err)
-	}
-
-	return nil
-}
-
 // configureInterface configures the WireGuard interface
 func (w *WireGuardService) configureInterface() error {
 	w.logger.Infof("Configuring WireGuard interface: %s", w.config.WGInterface)
@@ -133,22 +136,43 @@ func (w *WireGuardService) configureInterface() error {
 	return nil
 }
 
-// AddPeer adds a new peer to the WireGuard interface
-func (w *WireGuardService) AddPeer(publicKey string, allowedIPs []string) error {
-	w.logger.Infof("Adding peer: %s with IPs: %v", publicKey, allowedIPs)
+// AddPeer adds a new peer to the WireGuard interface. The peer is leased
+// allowedIPs, or a free address from WG_SUBNET when none are given; the
+// leased addresses are returned.
+func (w *WireGuardService) AddPeer(publicKey string, allowedIPs []string) ([]string, error) {
+	if !w.AcceptingPeers() {
+		return nil, ErrNotAcceptingPeers
+	}
+	if w.nodeQuotaExceeded() {
+		return nil, ErrNodeQuotaExceeded
+	}
 
 	// Parse public key
 	peerKey, err := wgtypes.ParseKey(publicKey)
 	if err != nil {
-		return fmt.Errorf("invalid public key: %w", err)
+		return nil, fmt.Errorf("invalid public key: %w", err)
 	}
 
+	// Lease the tunnel addresses, keeping any previous lease to roll back to
+	previous, hadLease := w.ipam.Lease(publicKey)
+	if len(allowedIPs) == 0 {
+		allowedIPs, err = w.ipam.Allocate(publicKey)
+	} else {
+		allowedIPs, err = w.ipam.Reserve(publicKey, allowedIPs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w.logger.Infof("Adding peer: %s with IPs: %v", publicKey, allowedIPs)
+
 	// Convert string IPs to net.IPNet
 	var ipNets []net.IPNet
 	for _, ipStr := range allowedIPs {
 		_, ipNet, err := net.ParseCIDR(ipStr)
 		if err != nil {
-			return fmt.Errorf("invalid IP address: %s", ipStr)
+			w.restoreLease(publicKey, previous, hadLease)
+			return nil, fmt.Errorf("invalid IP address: %s", ipStr)
 		}
 		ipNets = append(ipNets, *ipNet)
 	}
@@ -157,28 +181,74 @@ func (w *WireGuardService) AddPeer(publicKey string, allowedIPs []string) error
 	config := wgtypes.Config{
 		Peers: []wgtypes.PeerConfig{
 			{
-				PublicKey:  peerKey,
-				AllowedIPs: ipNets,
+				PublicKey:         peerKey,
+				ReplaceAllowedIPs: true,
+				AllowedIPs:        ipNets,
 			},
 		},
 	}
 
 	if err := w.device.ConfigureDevice(w.config.WGInterface, config); err != nil {
-		return fmt.Errorf("failed to add peer: %w", err)
+		w.restoreLease(publicKey, previous, hadLease)
+		return nil, fmt.Errorf("failed to add peer: %w", err)
 	}
 
 	// Store peer information
-	w.peersMutex.Lock()
-	w.peers[publicKey] = &types.Peer{
+	peer := &types.Peer{
 		PublicKey:  publicKey,
 		AllowedIPs: allowedIPs,
-		LastSeen:   time.Now(),
-		IsActive:   true,
+		State:      PeerPending,
+		CreatedAt:  time.Now(),
 	}
+
+	w.peersMutex.Lock()
+	if existing, exists := w.peers[publicKey]; exists {
+		// Re-adding a peer only changes its addresses
+		existing.AllowedIPs = allowedIPs
+		peer = existing
+	} else {
+		// A new peer starts a new session with fresh counters
+		w.recreated(peer)
+		w.usage[publicKey] = &types.PeerUsage{Day: today(), Generation: peer.Generation, SessionID: peer.Generation}
+	}
+	w.peers[publicKey] = peer
 	w.peersMutex.Unlock()
 
+	if err := w.store.Put(peersBucket, publicKey, peer); err != nil {
+		w.logger.Errorf("Failed to persist peer %s: %v", publicKey, err)
+	}
+	w.shape(publicKey, allowedIPs)
+
 	w.logger.Infof("Peer %s added successfully", publicKey)
-	return nil
+	return allowedIPs, nil
+}
+
+// recreated marks peer as newly created on the device, with byte counters
+// starting from zero
+func (w *WireGuardService) recreated(peer *types.Peer) {
+	// Generations come from the clock so they also differ across restarts
+	for {
+		last := w.generation.Load()
+		next := max(uint64(time.Now().UnixNano()), last+1)
+		if w.generation.CompareAndSwap(last, next) {
+			peer.Generation = next
+			break
+		}
+	}
+	peer.BytesRx, peer.BytesTx = 0, 0
+}
+
+// restoreLease puts back the lease a peer held before a failed AddPeer
+func (w *WireGuardService) restoreLease(publicKey string, previous *types.IPLease, hadLease bool) {
+	var err error
+	if hadLease {
+		_, err = w.ipam.Reserve(publicKey, previous.Addresses)
+	} else {
+		err = w.ipam.Release(publicKey)
+	}
+	if err != nil {
+		w.logger.Errorf("Failed to restore address lease of peer %s: %v", publicKey, err)
+	}
 }
 
 // RemovePeer removes a peer from the WireGuard interface
@@ -208,54 +278,180 @@ func (w *WireGuardService) RemovePeer(publicKey string) error {
 	// Remove from local storage
 	w.peersMutex.Lock()
 	delete(w.peers, publicKey)
+	w.forgetUsage(publicKey)
 	w.peersMutex.Unlock()
 
+	if err := w.store.Delete(peersBucket, publicKey); err != nil {
+		w.logger.Errorf("Failed to delete stored peer %s: %v", publicKey, err)
+	}
+	if err := w.ipam.Release(publicKey); err != nil {
+		w.logger.Errorf("Failed to release addresses of peer %s: %v", publicKey, err)
+	}
+	w.unshape(publicKey)
+
 	w.logger.Infof("Peer %s removed successfully", publicKey)
 	return nil
 }
 
-// GetPeers returns all peers
+// RemoveAllPeers removes every peer configured on the WireGuard interface
+func (w *WireGuardService) RemoveAllPeers() error {
+	w.logger.Info("Removing all peers")
+
+	if err := w.device.ConfigureDevice(w.config.WGInterface, wgtypes.Config{ReplacePeers: true}); err != nil {
+		return fmt.Errorf("failed to remove peers: %w", err)
+	}
+
+	w.peersMutex.Lock()
+	removed := w.peers
+	for publicKey := range w.usage {
+		w.forgetUsage(publicKey)
+	}
+	w.peers = make(map[string]*types.Peer)
+	w.peersMutex.Unlock()
+
+	for publicKey := range removed {
+		w.unshape(publicKey)
+	}
+
+	stored, err := w.storedPeers()
+	if err != nil {
+		w.logger.Errorf("Failed to load stored peers: %v", err)
+	}
+	for publicKey := range stored {
+		if err := w.store.Delete(peersBucket, publicKey); err != nil {
+			w.logger.Errorf("Failed to delete stored peer %s: %v", publicKey, err)
+		}
+	}
+	for _, lease := range w.ipam.Leases() {
+		if err := w.ipam.Release(lease.PublicKey); err != nil {
+			w.logger.Errorf("Failed to release addresses of peer %s: %v", lease.PublicKey, err)
+		}
+	}
+
+	return nil
+}
+
+// SetAcceptingPeers controls whether AddPeer accepts new peers
+func (w *WireGuardService) SetAcceptingPeers(accepting bool) {
+	w.peersMutex.Lock()
+	w.acceptingPeers = accepting
+	w.peersMutex.Unlock()
+}
+
+// AcceptingPeers reports whether new peers can be added
+func (w *WireGuardService) AcceptingPeers() bool {
+	w.peersMutex.RLock()
+	defer w.peersMutex.RUnlock()
+
+	return w.acceptingPeers
+}
+
+// GetPeers returns a snapshot of all peers. The stats cycle keeps updating
+// the live peers, so callers get copies they can read without the lock.
 func (w *WireGuardService) GetPeers() map[string]*types.Peer {
 	w.peersMutex.RLock()
 	defer w.peersMutex.RUnlock()
 
-	peers := make(map[string]*types.Peer)
+	peers := make(map[string]*types.Peer, len(w.peers))
 	for key, peer := range w.peers {
-		peers[key] = peer
+		snapshot := *peer
+		peers[key] = &snapshot
 	}
 
 	return peers
 }
 
-// GetPeer returns a specific peer
+// GetPeer returns a snapshot of a specific peer
 func (w *WireGuardService) GetPeer(publicKey string) (*types.Peer, bool) {
 	w.peersMutex.RLock()
 	defer w.peersMutex.RUnlock()
 
 	peer, exists := w.peers[publicKey]
-	return peer, exists
+	if !exists {
+		return nil, false
+	}
+	snapshot := *peer
+	return &snapshot, true
 }
 
-// UpdatePeerStats updates peer statistics
+// UpdatePeerStats refreshes peer counters from the device and derives each
+// peer's connectivity state from its last handshake and traffic. Calls are
+// serialised so that an older reading is never applied after a newer one, and
+// counters that drop are treated as the peer being re-created on the device.
 func (w *WireGuardService) UpdatePeerStats() error {
+	w.statsMutex.Lock()
+	defer w.statsMutex.Unlock()
+
+	// A peer re-created after these are taken is skipped, as the reading may
+	// still show the counters of its previous instance
+	w.peersMutex.RLock()
+	generations := make(map[string]uint64, len(w.peers))
+	for publicKey, peer := range w.peers {
+		generations[publicKey] = peer.Generation
+	}
+	w.peersMutex.RUnlock()
+
 	device, err := w.device.Device(w.config.WGInterface)
 	if err != nil {
 		return fmt.Errorf("failed to get device: %w", err)
 	}
 
+	now := time.Now()
+	day := today()
+	prepaid := w.prepaidQuotas()
+
+	var (
+		changes []stateChange
+		events  []quotaEvent
+		metered []string
+		reset   []string
+		total   int64
+	)
+
 	w.peersMutex.Lock()
-	defer w.peersMutex.Unlock()
-
-	for _, peer := range device.Peers {
-		peerKey := peer.PublicKey.String()
-		if storedPeer, exists := w.peers[peerKey]; exists {
-			storedPeer.BytesRx = peer.ReceiveBytes
-			storedPeer.BytesTx = peer.TransmitBytes
-			storedPeer.LastSeen = time.Now()
-			storedPeer.IsActive = true
+	for i := range device.Peers {
+		publicKey := device.Peers[i].PublicKey.String()
+		storedPeer, exists := w.peers[publicKey]
+		if generation, known := generations[publicKey]; !exists || !known || generation != storedPeer.Generation {
+			continue
+		}
+		if device.Peers[i].ReceiveBytes < storedPeer.BytesRx || device.Peers[i].TransmitBytes < storedPeer.BytesTx {
+			// Readings are serialised, so the peer was re-created on the
+			// device behind the node's back and counts from zero again
+			w.logger.Infof("Counters of peer %s were reset on the device", publicKey)
+			w.recreated(storedPeer)
+			reset = append(reset, publicKey)
+		}
+
+		delta, peerEvents := w.meter(publicKey, storedPeer.Generation, device.Peers[i].ReceiveBytes, device.Peers[i].TransmitBytes, day, prepaid)
+		if delta > 0 {
+			metered = append(metered, publicKey)
+		}
+		total += delta
+		events = append(events, peerEvents...)
+
+		if change, changed := w.observe(storedPeer, &device.Peers[i], now); changed {
+			changes = append(changes, change)
 		}
 	}
+	crossed, lifted := w.meterNode(total, day)
+	recreated := make([]types.Peer, 0, len(reset))
+	for _, publicKey := range reset {
+		recreated = append(recreated, *w.peers[publicKey])
+	}
+	w.peersMutex.Unlock()
 
+	for _, peer := range recreated {
+		if err := w.store.Put(peersBucket, peer.PublicKey, peer); err != nil {
+			w.logger.Errorf("Failed to persist peer %s: %v", peer.PublicKey, err)
+		}
+	}
+	w.notifyChanges(changes)
+	if len(metered) > 0 || len(reset) > 0 || len(events) > 0 || crossed || lifted {
+		w.saveUsage(append(metered, reset...))
+	}
+	w.enforceQuotas(events)
+	w.enforceNodeQuota(crossed, lifted)
 	return nil
 }
 
@@ -273,7 +469,7 @@ func (w *WireGuardService) GetTotalBandwidth() (int64, int64) {
 	return totalRx, totalTx
 }
 
-// GetConnectedPeersCount returns the number of connected peers
+// GetConnectedPeersCount returns the number of peers in the connected state
 func (w *WireGuardService) GetConnectedPeersCount() int {
 	w.peersMutex.RLock()
 	defer w.peersMutex.RUnlock()
@@ -288,6 +484,11 @@ func (w *WireGuardService) GetConnectedPeersCount() int {
 	return count
 }
 
+// AddressPool returns the allocator of tunnel addresses
+func (w *WireGuardService) AddressPool() *ipam.Allocator {
+	return w.ipam
+}
+
 // GetPublicKey returns the node's public key
 func (w *WireGuardService) GetPublicKey() string {
 	return w.config.WGPublicKey
@@ -298,7 +499,13 @@ func (w *WireGuardService) GetInterfaceName() string {
 	return w.config.WGInterface
 }
 
-// Close closes the WireGuard service
+// Backend returns the kind of WireGuard backend running the interface
+func (w *WireGuardService) Backend() string {
+	return w.device.Name()
+}
+
+// Close tears down what the node set up on the interface and closes the
+// WireGuard service
 func (w *WireGuardService) Close() error {
 	if w.device != nil {
 		return w.device.Close()
@@ -0,0 +1,214 @@
+package wireguard
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dvpn-node/internal/store"
+	"dvpn-node/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const testInterface = "wg-test"
+
+// replayBackend returns one queued device reading per Device call, repeating
+// the last one when the queue runs out
+type replayBackend struct {
+	readings []wgtypes.Device
+}
+
+func (b *replayBackend) Name() string { return "replay" }
+
+func (b *replayBackend) Device(name string) (*wgtypes.Device, error) {
+	reading := b.readings[0]
+	if len(b.readings) > 1 {
+		b.readings = b.readings[1:]
+	}
+	return &reading, nil
+}
+
+func (b *replayBackend) ConfigureDevice(name string, config wgtypes.Config) error { return nil }
+
+func (b *replayBackend) Close() error { return nil }
+
+func newTestService(t *testing.T, backend Backend) *WireGuardService {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "node.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return &WireGuardService{
+		config: &types.NodeConfig{WGInterface: testInterface},
+		logger: logger,
+		device: backend,
+		store:  db,
+		peers:  make(map[string]*types.Peer),
+		notify: func(types.WebSocketMessage) {},
+		usage:  make(map[string]*types.PeerUsage),
+	}
+}
+
+func newTestPeerKey(t *testing.T) wgtypes.Key {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key.PublicKey()
+}
+
+func reading(key wgtypes.Key, rx, tx int64) wgtypes.Device {
+	return wgtypes.Device{Peers: []wgtypes.Peer{{PublicKey: key, ReceiveBytes: rx, TransmitBytes: tx}}}
+}
+
+func TestUpdatePeerStatsTreatsCounterDropAsReset(t *testing.T) {
+	key := newTestPeerKey(t)
+	backend := &replayBackend{readings: []wgtypes.Device{
+		reading(key, 1000, 100),
+		reading(key, 400, 40), // peer re-created on the device outside the node
+		reading(key, 1200, 150),
+	}}
+	w := newTestService(t, backend)
+
+	peer := &types.Peer{PublicKey: key.String()}
+	w.recreated(peer)
+	w.peers[peer.PublicKey] = peer
+	w.usage[peer.PublicKey] = &types.PeerUsage{Day: today(), Generation: peer.Generation}
+	generation := peer.Generation
+
+	for i := 0; i < 3; i++ {
+		if err := w.UpdatePeerStats(); err != nil {
+			t.Fatalf("UpdatePeerStats: %v", err)
+		}
+	}
+
+	// 1100 before the reset, 440 counted from zero, then 910 more
+	usage := w.usage[peer.PublicKey]
+	if usage.SessionBytes != 2450 || usage.DailyBytes != 2450 {
+		t.Fatalf("usage = %d session, %d daily; want 2450 each", usage.SessionBytes, usage.DailyBytes)
+	}
+	if w.nodeUsage.Bytes != 2450 {
+		t.Fatalf("node usage = %d, want 2450", w.nodeUsage.Bytes)
+	}
+
+	current, _ := w.GetPeer(peer.PublicKey)
+	if current.Generation == generation || usage.Generation != current.Generation {
+		t.Fatalf("generation = %d (usage %d), want a new generation after the reset", current.Generation, usage.Generation)
+	}
+	if current.BytesRx != 1200 || current.BytesTx != 150 {
+		t.Fatalf("peer counters = %d/%d, want 1200/150", current.BytesRx, current.BytesTx)
+	}
+}
+
+func TestUpdatePeerStatsSkipsPeerRecreatedDuringRead(t *testing.T) {
+	key := newTestPeerKey(t)
+	w := newTestService(t, nil)
+
+	peer := &types.Peer{PublicKey: key.String()}
+	w.recreated(peer)
+	w.peers[peer.PublicKey] = peer
+	w.usage[peer.PublicKey] = &types.PeerUsage{Day: today(), Generation: peer.Generation}
+
+	// The peer is re-created between taking generations and reading the
+	// device, which still reports its previous instance
+	w.device = &recreatingBackend{
+		replayBackend: replayBackend{readings: []wgtypes.Device{reading(key, 5000, 500)}},
+		recreate: func() {
+			w.peersMutex.Lock()
+			defer w.peersMutex.Unlock()
+			w.recreated(w.peers[peer.PublicKey])
+		},
+	}
+
+	if err := w.UpdatePeerStats(); err != nil {
+		t.Fatalf("UpdatePeerStats: %v", err)
+	}
+	if usage := w.usage[peer.PublicKey]; usage.SessionBytes != 0 {
+		t.Fatalf("metered %d bytes from a reading of the previous instance", usage.SessionBytes)
+	}
+}
+
+// recreatingBackend runs recreate before returning each reading
+type recreatingBackend struct {
+	replayBackend
+	recreate func()
+}
+
+func (b *recreatingBackend) Device(name string) (*wgtypes.Device, error) {
+	b.recreate()
+	return b.replayBackend.Device(name)
+}
+
+func TestMeterCountsOnlyRealResets(t *testing.T) {
+	w := newTestService(t, nil)
+	day := today()
+	prepaid := map[string]int64{}
+
+	steps := []struct {
+		name       string
+		generation uint64
+		rx, tx     int64
+		want       int64 // session bytes after the step
+	}{
+		{"first reading", 1, 1000, 0, 1000},
+		{"traffic", 1, 1500, 100, 1600},
+		{"stale reading", 1, 1200, 50, 1600},
+		{"caught up", 1, 1600, 100, 1700},
+		{"peer re-created", 2, 300, 20, 2020},
+		{"traffic after re-creation", 2, 400, 20, 2120},
+	}
+
+	w.usage["peer"] = &types.PeerUsage{Day: day, Generation: 1}
+	for _, step := range steps {
+		w.meter("peer", step.generation, step.rx, step.tx, day, prepaid)
+		if got := w.usage["peer"].SessionBytes; got != step.want {
+			t.Fatalf("%s: session bytes = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
+
+func TestReapReasonSkipsIdleWithStaleStats(t *testing.T) {
+	w := newTestService(t, nil)
+	w.config.WGReapIdleTimeout = time.Minute
+
+	now := time.Now()
+	peer := &types.Peer{
+		PublicKey:     newTestPeerKey(t).String(),
+		CreatedAt:     now.Add(-time.Hour),
+		LastHandshake: now.Add(-10 * time.Minute),
+	}
+
+	if reason, due := w.reapReason(peer, now, true); !due || reason != ReapIdle {
+		t.Fatalf("reapReason with fresh stats = %q, %v; want %q, true", reason, due, ReapIdle)
+	}
+	if reason, due := w.reapReason(peer, now, false); due {
+		t.Fatalf("reapReason with stale stats reaped the peer as %q", reason)
+	}
+}
+
+func TestGetPeersReturnsSnapshots(t *testing.T) {
+	key := newTestPeerKey(t)
+	w := newTestService(t, &replayBackend{readings: []wgtypes.Device{reading(key, 1000, 100)}})
+
+	peer := &types.Peer{PublicKey: key.String()}
+	w.recreated(peer)
+	w.peers[peer.PublicKey] = peer
+	w.usage[peer.PublicKey] = &types.PeerUsage{Day: today(), Generation: peer.Generation}
+
+	snapshot := w.GetPeers()[peer.PublicKey]
+	if err := w.UpdatePeerStats(); err != nil {
+		t.Fatalf("UpdatePeerStats: %v", err)
+	}
+	if snapshot.BytesRx != 0 || snapshot.State != "" {
+		t.Fatalf("snapshot changed by the stats cycle: %d bytes, state %q", snapshot.BytesRx, snapshot.State)
+	}
+}